@@ -0,0 +1,142 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveConfigSource(t *testing.T) {
+	cases := []struct {
+		url  string
+		want interface{}
+	}{
+		{"git+https://fuchsia.googlesource.com/cobalt-config", gitConfigSource{}},
+		{"sso://fuchsia/cobalt-config", gitConfigSource{}},
+		{"https://example.com/config.tar.gz", httpTarballConfigSource{}},
+		{"gs://my-bucket/config.tar.gz", gcsConfigSource{}},
+		{"oci://registry.example.com/cobalt-config:latest", ociConfigSource{}},
+	}
+
+	for _, c := range cases {
+		source, err := ResolveConfigSource(c.url, time.Second, nil)
+		if err != nil {
+			t.Errorf("Unexpected error resolving '%v': %v", c.url, err)
+			continue
+		}
+
+		switch c.want.(type) {
+		case gitConfigSource:
+			if _, ok := source.(gitConfigSource); !ok {
+				t.Errorf("Expected a gitConfigSource for '%v', got %T", c.url, source)
+			}
+		case httpTarballConfigSource:
+			if _, ok := source.(httpTarballConfigSource); !ok {
+				t.Errorf("Expected an httpTarballConfigSource for '%v', got %T", c.url, source)
+			}
+		case gcsConfigSource:
+			if _, ok := source.(gcsConfigSource); !ok {
+				t.Errorf("Expected a gcsConfigSource for '%v', got %T", c.url, source)
+			}
+		case ociConfigSource:
+			if _, ok := source.(ociConfigSource); !ok {
+				t.Errorf("Expected an ociConfigSource for '%v', got %T", c.url, source)
+			}
+		}
+	}
+}
+
+// tarGzOf builds an in-memory .tar.gz containing a single regular file entry
+// with the given name and contents, for feeding to extractTarGz.
+func tarGzOf(t *testing.T, name string, contents string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name: name,
+		Mode: 0644,
+		Size: int64(len(contents)),
+	}); err != nil {
+		t.Fatalf("Unexpected error writing tar header: %v", err)
+	}
+	if _, err := tw.Write([]byte(contents)); err != nil {
+		t.Fatalf("Unexpected error writing tar entry: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Unexpected error closing tar writer: %v", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("Unexpected error closing gzip writer: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// Tests that extractTarGz rejects a tarball entry whose name would escape
+// destDir via a ".." segment, instead of writing outside of it (a
+// "zip-slip" path-traversal attack).
+func TestExtractTarGzRejectsPathTraversal(t *testing.T) {
+	destDir, err := ioutil.TempDir("", "cobalt_config_extract_test")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(destDir)
+
+	tarball := tarGzOf(t, "../../etc/passwd", "pwned")
+	if err := extractTarGz(bytes.NewReader(tarball), destDir); err == nil {
+		t.Fatal("Expected an error extracting a tarball entry that escapes destDir.")
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(filepath.Dir(destDir)), "etc", "passwd")); !os.IsNotExist(err) {
+		t.Errorf("Expected the traversal entry not to have been written outside destDir.")
+	}
+}
+
+// Tests that extractTarGz rejects a tarball entry with an absolute path.
+func TestExtractTarGzRejectsAbsolutePath(t *testing.T) {
+	destDir, err := ioutil.TempDir("", "cobalt_config_extract_test")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(destDir)
+
+	tarball := tarGzOf(t, "/etc/passwd", "pwned")
+	if err := extractTarGz(bytes.NewReader(tarball), destDir); err == nil {
+		t.Fatal("Expected an error extracting a tarball entry with an absolute path.")
+	}
+}
+
+// Tests that extractTarGz still accepts a well formed tarball whose entries
+// stay within destDir.
+func TestExtractTarGzAcceptsWellFormedTarball(t *testing.T) {
+	destDir, err := ioutil.TempDir("", "cobalt_config_extract_test")
+	if err != nil {
+		t.Fatalf("Unexpected error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(destDir)
+
+	tarball := tarGzOf(t, "customer/project/metrics.yaml", "contents")
+	if err := extractTarGz(bytes.NewReader(tarball), destDir); err != nil {
+		t.Fatalf("Unexpected error extracting a well formed tarball: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(filepath.Join(destDir, "customer", "project", "metrics.yaml"))
+	if err != nil {
+		t.Fatalf("Unexpected error reading extracted file: %v", err)
+	}
+	if string(got) != "contents" {
+		t.Errorf("Expected extracted file contents 'contents', got %q", got)
+	}
+}
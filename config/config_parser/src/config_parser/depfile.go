@@ -0,0 +1,112 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains logic for grouping the yaml files under a config_dir by
+// the project they belong to, so that a depfile can tell a build system
+// incremental rebuilds are unnecessary when an unrelated project's yaml
+// changes.
+
+package config_parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// DepFormat selects the syntax used to write a depfile: Ninja and GN both
+// expect "output: input input ...", escaping spaces with a backslash, while
+// Make additionally requires escaping '$' as '$$'.
+type DepFormat int
+
+const (
+	GNDepFormat DepFormat = iota
+	NinjaDepFormat
+	MakeDepFormat
+)
+
+// ParseDepFormat converts the string value of the --dep_format flag into a
+// DepFormat.
+func ParseDepFormat(s string) (DepFormat, error) {
+	switch s {
+	case "", "gn":
+		return GNDepFormat, nil
+	case "ninja":
+		return NinjaDepFormat, nil
+	case "make":
+		return MakeDepFormat, nil
+	default:
+		return GNDepFormat, fmt.Errorf("'%v' is an invalid dep_format. 'gn', 'ninja' and 'make' are the only valid values.", s)
+	}
+}
+
+// EscapeDepFilePath escapes a single path for inclusion in a depfile rule
+// under the given format.
+func EscapeDepFilePath(path string, format DepFormat) string {
+	escaped := ""
+	for _, r := range path {
+		if r == ' ' || r == '\\' {
+			escaped += "\\"
+		}
+		if format == MakeDepFormat && r == '$' {
+			escaped += "$"
+		}
+		escaped += string(r)
+	}
+	return escaped
+}
+
+// GetConfigFilesListByProject walks configDir (which is expected to contain
+// one subdirectory per customer, each containing one subdirectory per
+// project) and returns a map from "customer/project" to the list of yaml
+// files under that project's directory. This lets writeDepFile emit a rule
+// per project instead of lumping every file in configDir under every output.
+func GetConfigFilesListByProject(configDir string) (filesByProject map[string][]string, err error) {
+	filesByProject = map[string][]string{}
+
+	customers, err := ioutil.ReadDir(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading config_dir '%v': %v", configDir, err)
+	}
+
+	for _, customer := range customers {
+		if !customer.IsDir() {
+			continue
+		}
+		customerDir := filepath.Join(configDir, customer.Name())
+
+		projects, err := ioutil.ReadDir(customerDir)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading customer directory '%v': %v", customerDir, err)
+		}
+
+		for _, project := range projects {
+			if !project.IsDir() {
+				continue
+			}
+			projectDir := filepath.Join(customerDir, project.Name())
+			key := customer.Name() + "/" + project.Name()
+
+			err := filepath.Walk(projectDir, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if info.IsDir() {
+					return nil
+				}
+				if filepath.Ext(path) != ".yaml" {
+					return nil
+				}
+				filesByProject[key] = append(filesByProject[key], path)
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Error walking project directory '%v': %v", projectDir, err)
+			}
+		}
+	}
+
+	return filesByProject, nil
+}
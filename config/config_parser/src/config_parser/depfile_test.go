@@ -0,0 +1,72 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestParseDepFormat(t *testing.T) {
+	cases := map[string]DepFormat{
+		"":      GNDepFormat,
+		"gn":    GNDepFormat,
+		"ninja": NinjaDepFormat,
+		"make":  MakeDepFormat,
+	}
+	for in, want := range cases {
+		got, err := ParseDepFormat(in)
+		if err != nil {
+			t.Errorf("Unexpected error for '%v': %v", in, err)
+		}
+		if got != want {
+			t.Errorf("ParseDepFormat(%v) = %v, want %v", in, got, want)
+		}
+	}
+
+	if _, err := ParseDepFormat("bogus"); err == nil {
+		t.Errorf("Expected an error for an invalid dep_format.")
+	}
+}
+
+func TestEscapeDepFilePath(t *testing.T) {
+	if got := EscapeDepFilePath("a b", NinjaDepFormat); got != "a\\ b" {
+		t.Errorf("Unexpected escaping for ninja: %v", got)
+	}
+	if got := EscapeDepFilePath("a$b", MakeDepFormat); got != "a$$b" {
+		t.Errorf("Unexpected escaping for make: %v", got)
+	}
+}
+
+func TestGetConfigFilesListByProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobalt_config_dir_test")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ledgerDir := path.Join(dir, "fuchsia", "ledger")
+	if err := os.MkdirAll(ledgerDir, 0755); err != nil {
+		t.Fatalf("Error creating project dir: %v", err)
+	}
+	if err := ioutil.WriteFile(path.Join(ledgerDir, "config.yaml"), []byte(""), 0644); err != nil {
+		t.Fatalf("Error writing config file: %v", err)
+	}
+
+	filesByProject, err := GetConfigFilesListByProject(dir)
+	if err != nil {
+		t.Fatalf("Error getting files by project: %v", err)
+	}
+
+	files, ok := filesByProject["fuchsia/ledger"]
+	if !ok {
+		t.Fatalf("Expected a 'fuchsia/ledger' entry, got %v", filesByProject)
+	}
+	if len(files) != 1 {
+		t.Errorf("Expected 1 file for 'fuchsia/ledger', got %v", files)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains MultiError, the aggregation type parseCustomerList,
+// populateProjectList, populateProjectConfig and config_validator's
+// validate* helpers use so a single run over a large customers yaml can
+// report every problem it finds instead of stopping at the first one. Each
+// error is wrapped in a FieldError carrying the fully-qualified path to the
+// offending value (e.g. "customers[3].projects[1].contact") and, where the
+// failure falls into one of the kinds below, a sentinel that errors.Is can
+// match against regardless of the specific message.
+
+package config_parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel error kinds. FieldError wraps one of these (via %w) so that
+// errors.Is(err, ErrDuplicateId) works no matter what path or message the
+// FieldError carries.
+var (
+	ErrUnknownField    = errors.New("unknown field")
+	ErrDuplicateId     = errors.New("duplicate id")
+	ErrInvalidName     = errors.New("invalid name")
+	ErrMissingField    = errors.New("missing field")
+	ErrExpiredMetadata = errors.New("expired metadata")
+)
+
+// FieldError is a single parse or validation failure at path, a
+// fully-qualified, dotted/indexed location such as
+// "customers[3].projects[1].contact".
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+// NewFieldError wraps err as having occurred at path. If kind is non-nil, it
+// should be one of the Err* sentinels above, allowing errors.Is(err, kind) to
+// match; pass nil for failures that don't fall into one of those kinds.
+func NewFieldError(path string, kind error, format string, args ...interface{}) *FieldError {
+	err := fmt.Errorf(format, args...)
+	if kind != nil {
+		err = fmt.Errorf("%w: %v", kind, err)
+	}
+	return &FieldError{Path: path, Err: err}
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError aggregates every FieldError (or, for callers that don't have a
+// path handy, any plain error) found in one parse or validation pass.
+type MultiError struct {
+	Errors []error
+}
+
+// Append adds err to m, unless err is nil, in which case it is a no-op. It
+// returns m so that calls can be chained.
+func (m *MultiError) Append(err error) *MultiError {
+	if err == nil {
+		return m
+	}
+	m.Errors = append(m.Errors, err)
+	return m
+}
+
+// ErrorOrNil returns m as an error if it contains any, or nil if it is
+// empty. This is the usual way to return a MultiError from a function that
+// is declared to return plain error: 'return errs.ErrorOrNil()'.
+func (m *MultiError) ErrorOrNil() error {
+	if m == nil || len(m.Errors) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *MultiError) Error() string {
+	lines := make([]string, len(m.Errors))
+	for i, err := range m.Errors {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap lets errors.Is/errors.As search every error in m, per the
+// multi-error convention introduced in Go 1.20.
+func (m *MultiError) Unwrap() []error {
+	return m.Errors
+}
@@ -0,0 +1,53 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMultiErrorEmptyIsNil(t *testing.T) {
+	m := &MultiError{}
+	if m.ErrorOrNil() != nil {
+		t.Error("Expected ErrorOrNil to return nil for an empty MultiError.")
+	}
+}
+
+func TestMultiErrorAppendNilIsNoOp(t *testing.T) {
+	m := &MultiError{}
+	m.Append(nil)
+	if len(m.Errors) != 0 {
+		t.Errorf("Expected Append(nil) to be a no-op, got %v", m.Errors)
+	}
+}
+
+func TestMultiErrorAggregatesAll(t *testing.T) {
+	m := &MultiError{}
+	m.Append(NewFieldError("customers[0].customer_name", ErrInvalidName, "invalid name '%v'", "1bad"))
+	m.Append(NewFieldError("customers[1].projects[0].id", ErrDuplicateId, "duplicate id %v", 5))
+
+	err := m.ErrorOrNil()
+	if err == nil {
+		t.Fatal("Expected a non-nil error for a MultiError with entries.")
+	}
+
+	if !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Expected errors.Is to find ErrInvalidName in %v", err)
+	}
+	if !errors.Is(err, ErrDuplicateId) {
+		t.Errorf("Expected errors.Is to find ErrDuplicateId in %v", err)
+	}
+	if errors.Is(err, ErrMissingField) {
+		t.Error("errors.Is unexpectedly matched a kind that wasn't present.")
+	}
+}
+
+func TestFieldErrorPathInMessage(t *testing.T) {
+	err := NewFieldError("customers[3].projects[1].contact", ErrMissingField, "missing contact")
+	if got := err.Error(); got != "customers[3].projects[1].contact: missing field: missing contact" {
+		t.Errorf("Unexpected FieldError message: %v", got)
+	}
+}
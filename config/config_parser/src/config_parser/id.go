@@ -4,16 +4,78 @@
 
 // This file contains the code that computes ids from names.
 //
-// We use the Fowler-Noll-Vo hash function
+// By default, we use the Fowler-Noll-Vo hash function. Projects that have
+// observed real collisions under FNV (see TestIdFromNameCollision) may select
+// an alternative via the 'hash_algorithm' field in the customers yaml; see
+// HasherByName.
 
 package config_parser
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
 	"hash/fnv"
 )
 
-func IdFromName(name string) uint32 {
+// IdHasher computes the id used to identify a metric or report by name.
+// Cobalt ids are derived from names rather than assigned explicitly, so
+// which IdHasher a project uses is part of its identity: changing it changes
+// every id in the project and invalidates historical data the same way a
+// rename would.
+type IdHasher interface {
+	Hash(name string) uint32
+}
+
+// fnvHasher is the default IdHasher, preserved for backwards compatibility
+// with every project that predates the IdHasher abstraction.
+type fnvHasher struct{}
+
+func (fnvHasher) Hash(name string) uint32 {
 	hash := fnv.New32()
 	hash.Write([]byte(name))
 	return hash.Sum32()
 }
+
+// sha256Hasher is an alternative IdHasher for projects that want a lower
+// collision probability than 32-bit FNV-1 can offer, at the cost of being
+// more expensive to compute.
+type sha256Hasher struct{}
+
+func (sha256Hasher) Hash(name string) uint32 {
+	sum := sha256.Sum256([]byte(name))
+	return binary.BigEndian.Uint32(sum[:4])
+}
+
+// hashers maps the values accepted by the 'hash_algorithm' yaml field to the
+// IdHasher they select.
+var hashers = map[string]IdHasher{
+	"fnv":    fnvHasher{},
+	"sha256": sha256Hasher{},
+}
+
+// DefaultHasher is the IdHasher used by projects that don't set
+// 'hash_algorithm'.
+var DefaultHasher IdHasher = fnvHasher{}
+
+// HasherByName looks up the IdHasher named by the 'hash_algorithm' field of a
+// project. The empty string selects the default (fnv), to match the
+// behavior of projects that don't set the field at all.
+func HasherByName(name string) (IdHasher, error) {
+	if name == "" {
+		name = "fnv"
+	}
+	h, ok := hashers[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown hash_algorithm '%v'. Valid values are 'fnv' and 'sha256'.", name)
+	}
+	return h, nil
+}
+
+// IdFromName hashes name with the default (fnv) IdHasher. Most callers that
+// don't need to honor a project's 'hash_algorithm' override should use this;
+// callers that do should look up the project's IdHasher with HasherByName
+// and call Hash directly.
+func IdFromName(name string) uint32 {
+	return fnvHasher{}.Hash(name)
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains an OutputFormatter that renders a CobaltConfig as a
+// JSON Schema document describing the shape of the raw YAML config files,
+// rather than the config itself. This lets editors and CI validate a
+// project's YAML before it is ever run through this tool, using the same
+// rules that validateConfigV0/validateConfigV1 enforce at build time.
+
+package config_parser
+
+import (
+	"config"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// jsonSchema is a minimal representation of the subset of JSON Schema (draft
+// 2020-12) that we need: enums, required fields, and object/array nesting.
+// It is hand rolled instead of pulled from a library so the output has no
+// third party dependency.
+type jsonSchema struct {
+	Schema            string                 `json:"$schema,omitempty"`
+	Title             string                 `json:"title,omitempty"`
+	Type              string                 `json:"type,omitempty"`
+	Enum              []string               `json:"enum,omitempty"`
+	Properties        map[string]*jsonSchema `json:"properties,omitempty"`
+	PatternProperties map[string]*jsonSchema `json:"patternProperties,omitempty"`
+	Items             *jsonSchema            `json:"items,omitempty"`
+	Required          []string               `json:"required,omitempty"`
+	Minimum           *float64               `json:"minimum,omitempty"`
+}
+
+func minimum(v float64) *float64 {
+	return &v
+}
+
+// enumValues turns one of the generated `<Type>_name` maps (as used in e.g.
+// config.MetricDefinition_MetricType_name) into a sorted list of the
+// corresponding enum names, skipping the zero/unset value.
+func enumValues(names map[int32]string, unset string) (values []string) {
+	for _, name := range names {
+		if name == unset {
+			continue
+		}
+		values = append(values, name)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func metricPartSchema() *jsonSchema {
+	return &jsonSchema{
+		Type:     "object",
+		Required: []string{"description"},
+		Properties: map[string]*jsonSchema{
+			"description": {Type: "string"},
+		},
+	}
+}
+
+// eventCodesSchema describes the event_codes map. It does not encode the
+// cross-field constraint that event_codes keys must be less than or equal to
+// max_event_code: "key <= sibling value" can't be expressed in JSON Schema
+// directly, so that check is left to config_validator at build time.
+// event_codes' keys are the numeric event codes themselves rather than a
+// fixed set of field names, so this is expressed with patternProperties
+// (which matches member names against a regex), not properties (which only
+// matches literal member names).
+func eventCodesSchema() *jsonSchema {
+	return &jsonSchema{
+		Type: "object",
+		PatternProperties: map[string]*jsonSchema{
+			"^[0-9]+$": {Type: "string"},
+		},
+	}
+}
+
+func reportDefinitionSchema() *jsonSchema {
+	return &jsonSchema{
+		Type:     "object",
+		Required: []string{"report_name", "report_type"},
+		Properties: map[string]*jsonSchema{
+			"report_name": {Type: "string"},
+			"report_type": {Type: "string", Enum: enumValues(config.ReportDefinition_ReportType_name, "REPORT_TYPE_UNSET")},
+		},
+	}
+}
+
+func metricDefinitionSchema() *jsonSchema {
+	return &jsonSchema{
+		Type:     "object",
+		Required: []string{"metric_name", "time_zone_policy"},
+		Properties: map[string]*jsonSchema{
+			"metric_name":      {Type: "string"},
+			"time_zone_policy": {Type: "string", Enum: enumValues(config.MetricDefinition_TimeZonePolicy_name, "TIME_ZONE_POLICY_UNSET")},
+			"metric_type":      {Type: "string", Enum: enumValues(config.MetricDefinition_MetricType_name, "UNSET")},
+			"max_event_code":   {Type: "integer", Minimum: minimum(0)},
+			"event_codes":      eventCodesSchema(),
+			"reports": {
+				Type:  "array",
+				Items: reportDefinitionSchema(),
+			},
+		},
+	}
+}
+
+func encodingConfigSchema() *jsonSchema {
+	return &jsonSchema{
+		Type: "object",
+	}
+}
+
+// projectConfigSchema describes the top level YAML document for a single
+// project, covering both the Cobalt 0.1 (metric_configs/encoding_configs/
+// report_configs) and the Cobalt 1.0 (metric_definitions) shapes, since a
+// project config file uses one or the other depending on cobalt_version.
+func projectConfigSchema() *jsonSchema {
+	return &jsonSchema{
+		Schema: "https://json-schema.org/draft/2020-12/schema",
+		Title:  "Cobalt project config",
+		Type:   "object",
+		Properties: map[string]*jsonSchema{
+			"encoding_configs": {Type: "array", Items: encodingConfigSchema()},
+			"metric_configs":   {Type: "array", Items: metricPartSchema()},
+			"metric_definitions": {
+				Type:  "array",
+				Items: metricDefinitionSchema(),
+			},
+		},
+	}
+}
+
+// JSONSchemaOutput is an OutputFormatter that writes a JSON Schema for a
+// Cobalt project config. It does not depend on the contents of |c|; it
+// always emits the same schema document, because the purpose is to validate
+// *other* YAML files before they are ever parsed into a CobaltConfig.
+func JSONSchemaOutput(c *config.CobaltConfig) ([]byte, error) {
+	b, err := json.MarshalIndent(projectConfigSchema(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Error marshaling JSON schema: %v", err)
+	}
+	return append(b, '\n'), nil
+}
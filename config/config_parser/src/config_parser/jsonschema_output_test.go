@@ -0,0 +1,69 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"config"
+	"encoding/json"
+	"testing"
+)
+
+// Tests that JSONSchemaOutput produces well formed JSON with the expected
+// top level shape.
+func TestJSONSchemaOutput(t *testing.T) {
+	c := config.CobaltConfig{}
+	b, err := JSONSchemaOutput(&c)
+	if err != nil {
+		t.Fatalf("Error generating JSON schema: %v", err)
+	}
+
+	var s map[string]interface{}
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatalf("Generated schema is not valid JSON: %v", err)
+	}
+
+	if s["title"] != "Cobalt project config" {
+		t.Errorf("Unexpected schema title: %v", s["title"])
+	}
+
+	properties, ok := s["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Schema is missing a 'properties' object.")
+	}
+
+	for _, name := range []string{"metric_definitions", "metric_configs", "encoding_configs"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("Schema is missing a property for '%v'.", name)
+		}
+	}
+}
+
+// Tests that eventCodesSchema expresses its numeric keys via
+// patternProperties, not properties: "properties" only matches literal
+// member names, so a regex key there would never match a real event_codes
+// map.
+func TestEventCodesSchemaUsesPatternProperties(t *testing.T) {
+	b, err := json.Marshal(eventCodesSchema())
+	if err != nil {
+		t.Fatalf("Error marshaling eventCodesSchema: %v", err)
+	}
+
+	var s map[string]interface{}
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatalf("eventCodesSchema is not valid JSON: %v", err)
+	}
+
+	if _, ok := s["properties"]; ok {
+		t.Errorf("Expected eventCodesSchema to have no 'properties', got %v", s["properties"])
+	}
+
+	patternProperties, ok := s["patternProperties"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected eventCodesSchema to have a 'patternProperties' object, got %v", s)
+	}
+	if _, ok := patternProperties["^[0-9]+$"]; !ok {
+		t.Errorf("Expected patternProperties to key on '^[0-9]+$', got %v", patternProperties)
+	}
+}
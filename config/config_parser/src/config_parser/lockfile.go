@@ -0,0 +1,132 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains the representation of a lockfile that pins the exact
+// contents of a Cobalt config fetched with --repo_url, so that repeated runs
+// of this tool against the same --repo_url are guaranteed to produce the same
+// output, the same way a Go modules or dep lockfile pins dependency versions.
+
+package config_parser
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"time"
+
+	yaml "github.com/go-yaml/yaml"
+)
+
+// ConfigLock records the resolved state of a config fetched from a remote
+// source. CommitSha is the git commit that was read; ProjectHashes maps each
+// project's "customer/project" key to the sha256 of its serialized
+// ProjectConfig, so that a partial, silent change to a single project can be
+// detected even if the commit sha were to be reused across a force-push.
+type ConfigLock struct {
+	CommitSha     string            `yaml:"commit_sha"`
+	ProjectHashes map[string]string `yaml:"project_hashes"`
+}
+
+// ReadLockFile reads a ConfigLock from the yaml file at path.
+func ReadLockFile(path string) (lock ConfigLock, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return lock, fmt.Errorf("Error reading lockfile '%v': %v", path, err)
+	}
+
+	if err = yaml.Unmarshal(content, &lock); err != nil {
+		return lock, fmt.Errorf("Error parsing lockfile '%v': %v", path, err)
+	}
+
+	return lock, nil
+}
+
+// WriteLockFile writes lock to path as yaml.
+func WriteLockFile(path string, lock ConfigLock) error {
+	content, err := yaml.Marshal(&lock)
+	if err != nil {
+		return fmt.Errorf("Error serializing lockfile: %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("Error writing lockfile '%v': %v", path, err)
+	}
+
+	return nil
+}
+
+// NewConfigLock builds a ConfigLock for commitSha from the resolved project
+// configs, hashing each project's serialized config so individual project
+// changes can be detected.
+func NewConfigLock(commitSha string, configs []ProjectConfig) ConfigLock {
+	lock := ConfigLock{
+		CommitSha:     commitSha,
+		ProjectHashes: map[string]string{},
+	}
+
+	for _, c := range configs {
+		lock.ProjectHashes[projectLockKey(c)] = hashProjectConfig(c)
+	}
+
+	return lock
+}
+
+// CheckFrozen compares the freshly resolved configs and commitSha against an
+// existing lockfile, returning an error describing the mismatch if the
+// currently resolved config does not match what was locked.
+func CheckFrozen(lock ConfigLock, commitSha string, configs []ProjectConfig) error {
+	if lock.CommitSha != commitSha {
+		return fmt.Errorf("--frozen check failed: lockfile pins commit '%v' but HEAD is at '%v'.", lock.CommitSha, commitSha)
+	}
+
+	for _, c := range configs {
+		key := projectLockKey(c)
+		want, ok := lock.ProjectHashes[key]
+		if !ok {
+			return fmt.Errorf("--frozen check failed: project '%v' is not present in the lockfile.", key)
+		}
+		if got := hashProjectConfig(c); got != want {
+			return fmt.Errorf("--frozen check failed: project '%v' does not match the hash recorded in the lockfile.", key)
+		}
+	}
+
+	return nil
+}
+
+// ResolveRepoHeadSha runs `git ls-remote` against repoUrl and returns the
+// commit sha that HEAD currently points to. It is used both to populate a new
+// lockfile and to check an existing one with --frozen, independently of
+// whatever ref ReadConfigFromRepo actually checks out.
+func ResolveRepoHeadSha(repoUrl string, timeout time.Duration) (sha string, err error) {
+	cmd := exec.Command("git", "ls-remote", repoUrl, "HEAD")
+	timer := time.AfterFunc(timeout, func() {
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+	})
+	defer timer.Stop()
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("Error resolving HEAD of '%v': %v", repoUrl, err)
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("'git ls-remote %v HEAD' returned no output.", repoUrl)
+	}
+
+	return fields[0], nil
+}
+
+func projectLockKey(c ProjectConfig) string {
+	return fmt.Sprintf("%v/%v", c.CustomerName, c.ProjectName)
+}
+
+func hashProjectConfig(c ProjectConfig) string {
+	sum := sha256.Sum256([]byte(c.ProjectConfig.String()))
+	return fmt.Sprintf("%x", sum)
+}
@@ -0,0 +1,55 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"config"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLockFileRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobalt_config_lock_test")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	configs := []ProjectConfig{
+		{CustomerName: "fuchsia", ProjectName: "ledger"},
+	}
+	lock := NewConfigLock("deadbeef", configs)
+
+	lockPath := path.Join(dir, "cobalt_config.lock")
+	if err := WriteLockFile(lockPath, lock); err != nil {
+		t.Fatalf("Error writing lockfile: %v", err)
+	}
+
+	readLock, err := ReadLockFile(lockPath)
+	if err != nil {
+		t.Fatalf("Error reading lockfile: %v", err)
+	}
+
+	if readLock.CommitSha != "deadbeef" {
+		t.Errorf("Unexpected commit sha: %v", readLock.CommitSha)
+	}
+
+	if err := CheckFrozen(readLock, "deadbeef", configs); err != nil {
+		t.Errorf("CheckFrozen failed on an unmodified config: %v", err)
+	}
+
+	if err := CheckFrozen(readLock, "other_sha", configs); err == nil {
+		t.Errorf("CheckFrozen did not catch a commit sha mismatch.")
+	}
+
+	configs[0].ProjectConfig = config.CobaltConfig{
+		MetricDefinitions: []*config.MetricDefinition{{MetricName: "new_metric"}},
+	}
+	if err := CheckFrozen(readLock, "deadbeef", configs); err == nil {
+		t.Errorf("CheckFrozen did not catch a changed project.")
+	}
+}
@@ -0,0 +1,537 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file lets a deployment keep one base customers yaml and layer
+// per-environment (local/staging/prod) customizations on top of it as a
+// series of RFC 6902 JSON Patch or RFC 7396 JSON Merge Patch documents,
+// instead of maintaining a separate full copy of the customer list per
+// environment.
+
+package config_parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	yaml "github.com/go-yaml/yaml"
+)
+
+// OverlayFormat selects how an Overlay's Body is interpreted.
+type OverlayFormat int
+
+const (
+	// JSONPatch applies Body as an RFC 6902 JSON Patch document: a JSON
+	// array of {op, path, value|from} operations.
+	JSONPatch OverlayFormat = iota
+	// MergePatch applies Body as an RFC 7396 JSON Merge Patch document: a
+	// JSON object recursively merged over the target, where a null value
+	// deletes the corresponding member.
+	MergePatch
+)
+
+// Overlay is one per-environment customization layered on top of the base
+// customer list by ParseCustomerListWithOverlays. Name identifies the
+// overlay (typically the path of the file it was read from) so that any
+// error it causes can be attributed to it.
+type Overlay struct {
+	Name   string
+	Format OverlayFormat
+	Body   string
+}
+
+// ParseCustomerListWithOverlays parses base the same way parseCustomerList
+// does, after applying every overlay in patches to it, in order. base and
+// each overlay's Body are treated as JSON-compatible trees (the customers
+// yaml shape already matches what RFC 6902/7396 expect), so the same patch
+// semantics apply whether base was written as YAML or JSON. After each
+// overlay is applied, the result is checked for newly introduced duplicate
+// customer/project ids or names, same as parseCustomerList itself performs;
+// if one is found, the error names the offending overlay.
+func ParseCustomerListWithOverlays(base string, patches []Overlay) ([]ProjectConfig, error) {
+	var tree interface{}
+	if err := yaml.Unmarshal([]byte(base), &tree); err != nil {
+		return nil, fmt.Errorf("Error while parsing the base customer list: %v", err)
+	}
+	doc := toJSONValue(tree)
+
+	for _, p := range patches {
+		var err error
+		switch p.Format {
+		case JSONPatch:
+			doc, err = applyJSONPatch(doc, p.Body)
+		case MergePatch:
+			doc, err = applyMergePatch(doc, p.Body)
+		default:
+			err = fmt.Errorf("unknown overlay format %v", p.Format)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error applying overlay '%v': %v", p.Name, err)
+		}
+
+		if err := checkOverlayUniqueness(doc); err != nil {
+			return nil, fmt.Errorf("Overlay '%v' introduced a conflict: %v", p.Name, err)
+		}
+	}
+
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("Error re-encoding the customer list after applying overlays: %v", err)
+	}
+
+	l := []ProjectConfig{}
+	if err := parseCustomerList(string(encoded), &l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// toJSONValue converts a tree produced by yaml.Unmarshal (which uses
+// map[interface{}]interface{} for mappings) into one built only of
+// map[string]interface{}, []interface{} and scalars, so it round-trips
+// through encoding/json and can be walked by the JSON Pointer logic below.
+func toJSONValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, val := range vv {
+			m[fmt.Sprintf("%v", k)] = toJSONValue(val)
+		}
+		return m
+	case map[string]interface{}:
+		for k, val := range vv {
+			vv[k] = toJSONValue(val)
+		}
+		return vv
+	case []interface{}:
+		for i, e := range vv {
+			vv[i] = toJSONValue(e)
+		}
+		return vv
+	default:
+		return v
+	}
+}
+
+// checkOverlayUniqueness re-runs the same duplicate-id/name checks that
+// parseCustomerList performs on a fully assembled customer list, against the
+// intermediate doc tree produced by applying one overlay. It is
+// deliberately more lenient than parseCustomerList about missing/malformed
+// fields, since an overlay applied mid-sequence (before a later overlay
+// fills in a required field) need not be a complete, individually valid
+// customer list.
+func checkOverlayUniqueness(doc interface{}) error {
+	customers, ok := doc.([]interface{})
+	if !ok {
+		return fmt.Errorf("customer list must be a yaml/json list")
+	}
+
+	customerNames := map[string]bool{}
+	customerIds := map[string]bool{}
+	for _, cv := range customers {
+		customer, ok := cv.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if name, ok := customer["customer_name"].(string); ok {
+			if customerNames[name] {
+				return fmt.Errorf("duplicate customer_name '%v'", name)
+			}
+			customerNames[name] = true
+		}
+
+		if id, ok := customer["customer_id"]; ok {
+			key := fmt.Sprintf("%v", id)
+			if customerIds[key] {
+				return fmt.Errorf("duplicate customer_id '%v'", key)
+			}
+			customerIds[key] = true
+		}
+
+		projects, _ := customer["projects"].([]interface{})
+		projectNames := map[string]bool{}
+		projectIds := map[string]bool{}
+		for _, pv := range projects {
+			project, ok := pv.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if name, ok := project["name"].(string); ok {
+				if projectNames[name] {
+					return fmt.Errorf("duplicate project name '%v'", name)
+				}
+				projectNames[name] = true
+			}
+			if id, ok := project["id"]; ok {
+				key := fmt.Sprintf("%v", id)
+				if projectIds[key] {
+					return fmt.Errorf("duplicate project id '%v'", key)
+				}
+				projectIds[key] = true
+			}
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////
+// RFC 6902 JSON Patch.
+////////////////////////////////////////////////////////////////////////////
+
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+func applyJSONPatch(doc interface{}, body string) (interface{}, error) {
+	var ops []jsonPatchOp
+	if err := json.Unmarshal([]byte(body), &ops); err != nil {
+		return nil, fmt.Errorf("invalid JSON Patch document: %v", err)
+	}
+
+	for _, op := range ops {
+		var err error
+		switch op.Op {
+		case "add":
+			var value interface{}
+			if value, err = decodeValue(op.Value); err == nil {
+				doc, err = patchAdd(doc, op.Path, value)
+			}
+		case "remove":
+			doc, err = patchRemove(doc, op.Path)
+		case "replace":
+			var value interface{}
+			if value, err = decodeValue(op.Value); err == nil {
+				doc, err = patchReplace(doc, op.Path, value)
+			}
+		case "move":
+			var value interface{}
+			if value, err = pointerGet(doc, op.From); err == nil {
+				if doc, err = patchRemove(doc, op.From); err == nil {
+					doc, err = patchAdd(doc, op.Path, value)
+				}
+			}
+		case "copy":
+			var value interface{}
+			if value, err = pointerGet(doc, op.From); err == nil {
+				doc, err = patchAdd(doc, op.Path, deepCopyJSONValue(value))
+			}
+		case "test":
+			var value interface{}
+			if value, err = decodeValue(op.Value); err == nil {
+				err = patchTest(doc, op.Path, value)
+			}
+		default:
+			err = fmt.Errorf("unknown op '%v'", op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("op '%v' at '%v': %v", op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func decodeValue(raw json.RawMessage) (interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("invalid value: %v", err)
+	}
+	return v, nil
+}
+
+// splitPointer splits an RFC 6901 JSON Pointer into its unescaped tokens.
+func splitPointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("JSON pointer '%v' must start with '/'", path)
+	}
+	parts := strings.Split(path[1:], "/")
+	for i, p := range parts {
+		p = strings.ReplaceAll(p, "~1", "/")
+		p = strings.ReplaceAll(p, "~0", "~")
+		parts[i] = p
+	}
+	return parts, nil
+}
+
+func arrayIndex(t string, length int) (int, error) {
+	if t == "-" {
+		return length, nil
+	}
+	i, err := strconv.Atoi(t)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid array index '%v'", t)
+	}
+	return i, nil
+}
+
+func pointerGet(doc interface{}, path string) (interface{}, error) {
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := doc
+	for _, t := range tokens {
+		switch c := cur.(type) {
+		case map[string]interface{}:
+			v, ok := c[t]
+			if !ok {
+				return nil, fmt.Errorf("no member '%v'", t)
+			}
+			cur = v
+		case []interface{}:
+			idx, err := arrayIndex(t, len(c))
+			if err != nil {
+				return nil, err
+			}
+			if idx >= len(c) {
+				return nil, fmt.Errorf("array index '%v' out of bounds", t)
+			}
+			cur = c[idx]
+		default:
+			return nil, fmt.Errorf("cannot descend into a scalar at '%v'", t)
+		}
+	}
+	return cur, nil
+}
+
+// deepCopyJSONValue returns a copy of v in which every map[string]interface{}
+// and []interface{} reachable from v has been recursively duplicated, so
+// that mutating the result can never alias v. This is needed before the
+// "copy" op installs a value fetched by pointerGet at a new location: without
+// it, the source and destination would share the same underlying map/slice,
+// so a later op editing the copy would silently corrupt the original too.
+func deepCopyJSONValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, e := range vv {
+			m[k] = deepCopyJSONValue(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(vv))
+		for i, e := range vv {
+			s[i] = deepCopyJSONValue(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+func patchAdd(doc interface{}, path string, value interface{}) (interface{}, error) {
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return setAtPath(doc, tokens, value, true)
+}
+
+func patchReplace(doc interface{}, path string, value interface{}) (interface{}, error) {
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return setAtPath(doc, tokens, value, false)
+}
+
+// setAtPath returns doc with the member/element named by tokens set to
+// value. insert selects array semantics: true (for "add") inserts a new
+// element, shifting the rest up (or appends, for the "-" token); false (for
+// "replace") overwrites the existing element in place.
+func setAtPath(doc interface{}, tokens []string, value interface{}, insert bool) (interface{}, error) {
+	token := tokens[0]
+	rest := tokens[1:]
+
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			c[token] = value
+			return c, nil
+		}
+		child, ok := c[token]
+		if !ok {
+			return nil, fmt.Errorf("no member '%v'", token)
+		}
+		updated, err := setAtPath(child, rest, value, insert)
+		if err != nil {
+			return nil, err
+		}
+		c[token] = updated
+		return c, nil
+	case []interface{}:
+		idx, err := arrayIndex(token, len(c))
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			if insert {
+				if idx > len(c) {
+					return nil, fmt.Errorf("array index '%v' out of bounds", token)
+				}
+				c = append(c, nil)
+				copy(c[idx+1:], c[idx:])
+				c[idx] = value
+				return c, nil
+			}
+			if idx >= len(c) {
+				return nil, fmt.Errorf("array index '%v' out of bounds", token)
+			}
+			c[idx] = value
+			return c, nil
+		}
+		if idx >= len(c) {
+			return nil, fmt.Errorf("array index '%v' out of bounds", token)
+		}
+		updated, err := setAtPath(c[idx], rest, value, insert)
+		if err != nil {
+			return nil, err
+		}
+		c[idx] = updated
+		return c, nil
+	default:
+		return nil, fmt.Errorf("cannot descend into a scalar at '%v'", token)
+	}
+}
+
+func patchRemove(doc interface{}, path string) (interface{}, error) {
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("cannot remove the document root")
+	}
+	return removeAtPath(doc, tokens)
+}
+
+func removeAtPath(doc interface{}, tokens []string) (interface{}, error) {
+	token := tokens[0]
+	rest := tokens[1:]
+
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			if _, ok := c[token]; !ok {
+				return nil, fmt.Errorf("no member '%v'", token)
+			}
+			delete(c, token)
+			return c, nil
+		}
+		child, ok := c[token]
+		if !ok {
+			return nil, fmt.Errorf("no member '%v'", token)
+		}
+		updated, err := removeAtPath(child, rest)
+		if err != nil {
+			return nil, err
+		}
+		c[token] = updated
+		return c, nil
+	case []interface{}:
+		idx, err := arrayIndex(token, len(c))
+		if err != nil {
+			return nil, err
+		}
+		if idx >= len(c) {
+			return nil, fmt.Errorf("array index '%v' out of bounds", token)
+		}
+		if len(rest) == 0 {
+			return append(c[:idx], c[idx+1:]...), nil
+		}
+		updated, err := removeAtPath(c[idx], rest)
+		if err != nil {
+			return nil, err
+		}
+		c[idx] = updated
+		return c, nil
+	default:
+		return nil, fmt.Errorf("cannot descend into a scalar at '%v'", token)
+	}
+}
+
+func patchTest(doc interface{}, path string, value interface{}) error {
+	got, err := pointerGet(doc, path)
+	if err != nil {
+		return err
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(value)
+	if string(gotJSON) != string(wantJSON) {
+		return fmt.Errorf("test failed: value at path does not match")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////
+// RFC 7396 JSON Merge Patch.
+////////////////////////////////////////////////////////////////////////////
+
+func applyMergePatch(doc interface{}, body string) (interface{}, error) {
+	var patch interface{}
+	if err := json.Unmarshal([]byte(body), &patch); err != nil {
+		return nil, fmt.Errorf("invalid merge patch document: %v", err)
+	}
+	return mergePatch(doc, patch), nil
+}
+
+// mergePatch implements the algorithm from RFC 7396 section 2: a null
+// member in patch deletes the corresponding member of target; a non-object
+// patch replaces target outright; otherwise patch is merged into target
+// member by member, recursively. As an extension beyond the RFC (which only
+// defines merging into objects), a patch object applied to a JSON array has
+// its keys interpreted as array indices, so an overlay can reach into the
+// customers/projects arrays the same way a JSON Patch overlay's numeric path
+// segments do.
+func mergePatch(target, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	if targetArr, ok := target.([]interface{}); ok {
+		return mergePatchArray(targetArr, patchMap)
+	}
+
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = map[string]interface{}{}
+	}
+
+	for k, v := range patchMap {
+		if v == nil {
+			delete(targetMap, k)
+			continue
+		}
+		targetMap[k] = mergePatch(targetMap[k], v)
+	}
+	return targetMap
+}
+
+func mergePatchArray(target []interface{}, patch map[string]interface{}) []interface{} {
+	for k, v := range patch {
+		idx, err := strconv.Atoi(k)
+		if err != nil || idx < 0 || idx >= len(target) {
+			continue
+		}
+		target[idx] = mergePatch(target[idx], v)
+	}
+	return target
+}
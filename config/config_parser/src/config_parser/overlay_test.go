@@ -0,0 +1,139 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const overlayBaseYaml = `
+- customer_name: fuchsia
+  customer_id: 1
+  projects:
+    - name: ledger
+      id: 100
+      contact: bob
+    - name: module_usage_tracking
+      id: 101
+      contact: bob
+`
+
+func TestParseCustomerListWithOverlaysJSONPatch(t *testing.T) {
+	patches := []Overlay{
+		{
+			Name:   "staging.json",
+			Format: JSONPatch,
+			Body:   `[{"op": "replace", "path": "/0/projects/0/contact", "value": "staging-oncall"}]`,
+		},
+	}
+
+	l, err := ParseCustomerListWithOverlays(overlayBaseYaml, patches)
+	if err != nil {
+		t.Fatalf("Unexpected error applying a JSON Patch overlay: %v", err)
+	}
+
+	if len(l) != 2 {
+		t.Fatalf("Expected 2 projects, got %v", l)
+	}
+	if l[0].Contact != "staging-oncall" {
+		t.Errorf("Expected overlay to replace ledger's contact, got %v", l[0].Contact)
+	}
+	if l[1].Contact != "bob" {
+		t.Errorf("Expected module_usage_tracking's contact to be untouched, got %v", l[1].Contact)
+	}
+}
+
+func TestParseCustomerListWithOverlaysMergePatch(t *testing.T) {
+	patches := []Overlay{
+		{
+			Name:   "prod.json",
+			Format: MergePatch,
+			Body:   `{"0": {"projects": {"0": {"contact": "prod-oncall"}}}}`,
+		},
+	}
+
+	l, err := ParseCustomerListWithOverlays(overlayBaseYaml, patches)
+	if err != nil {
+		t.Fatalf("Unexpected error applying a Merge Patch overlay: %v", err)
+	}
+
+	if l[0].Contact != "prod-oncall" {
+		t.Errorf("Expected overlay to replace ledger's contact, got %v", l[0].Contact)
+	}
+}
+
+func TestParseCustomerListWithOverlaysAppliedInOrder(t *testing.T) {
+	patches := []Overlay{
+		{Name: "first.json", Format: JSONPatch, Body: `[{"op": "replace", "path": "/0/projects/0/contact", "value": "a"}]`},
+		{Name: "second.json", Format: JSONPatch, Body: `[{"op": "replace", "path": "/0/projects/0/contact", "value": "b"}]`},
+	}
+
+	l, err := ParseCustomerListWithOverlays(overlayBaseYaml, patches)
+	if err != nil {
+		t.Fatalf("Unexpected error applying overlays: %v", err)
+	}
+	if l[0].Contact != "b" {
+		t.Errorf("Expected the later overlay to win, got %v", l[0].Contact)
+	}
+}
+
+func TestParseCustomerListWithOverlaysReportsConflictingOverlay(t *testing.T) {
+	patches := []Overlay{
+		{
+			Name:   "bad-id.json",
+			Format: JSONPatch,
+			Body:   `[{"op": "replace", "path": "/0/projects/1/id", "value": 100}]`,
+		},
+	}
+
+	_, err := ParseCustomerListWithOverlays(overlayBaseYaml, patches)
+	if err == nil {
+		t.Fatal("Expected an error for an overlay that introduces a duplicate project id.")
+	}
+	if !strings.Contains(err.Error(), "bad-id.json") {
+		t.Errorf("Expected the error to name the offending overlay 'bad-id.json', got: %v", err)
+	}
+}
+
+// Tests that a "copy" op produces an independent duplicate of the value at
+// "from", per RFC 6902, instead of aliasing it: a later op mutating the copy
+// must not also mutate the original.
+func TestApplyJSONPatchCopyDoesNotAliasSource(t *testing.T) {
+	var doc interface{} = map[string]interface{}{
+		"a": map[string]interface{}{"x": float64(1)},
+	}
+
+	body := `[{"op": "copy", "from": "/a", "path": "/b"}, {"op": "replace", "path": "/b/x", "value": 999}]`
+	got, err := applyJSONPatch(doc, body)
+	if err != nil {
+		t.Fatalf("Unexpected error applying patch: %v", err)
+	}
+
+	m := got.(map[string]interface{})
+	a := m["a"].(map[string]interface{})
+	b := m["b"].(map[string]interface{})
+
+	if a["x"] != float64(1) {
+		t.Errorf("Expected '/a/x' to be untouched by an edit to the copy at '/b/x', got %v", a["x"])
+	}
+	if b["x"] != float64(999) {
+		t.Errorf("Expected '/b/x' to be 999, got %v", b["x"])
+	}
+}
+
+func TestParseCustomerListWithOverlaysInvalidPatch(t *testing.T) {
+	patches := []Overlay{
+		{Name: "broken.json", Format: JSONPatch, Body: `[{"op": "replace", "path": "/not/a/real/path", "value": "x"}]`},
+	}
+
+	_, err := ParseCustomerListWithOverlays(overlayBaseYaml, patches)
+	if err == nil {
+		t.Fatal("Expected an error for a patch referencing a nonexistent path.")
+	}
+	if !strings.Contains(err.Error(), "broken.json") {
+		t.Errorf("Expected the error to name the offending overlay 'broken.json', got: %v", err)
+	}
+}
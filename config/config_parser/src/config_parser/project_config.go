@@ -36,12 +36,25 @@ const (
 
 // Represents the configuration of a single project.
 type ProjectConfig struct {
-	CustomerName  string
-	CustomerId    uint32
-	ProjectName   string
-	ProjectId     uint32
-	Contact       string
+	CustomerName string
+	CustomerId   uint32
+	ProjectName  string
+	ProjectId    uint32
+	Contact      string
+	// CobaltVersion is derived from Version for code that only needs to
+	// distinguish the legacy 0.1 and 1.0 behaviors: CobaltVersion0 if
+	// Version.Major is 0, CobaltVersion1 otherwise. New code that needs to
+	// gate a feature to a version range should use Version directly with a
+	// VersionRange instead of adding another branch here.
 	CobaltVersion CobaltVersion
+	// Version is the semver parsed from the 'cobalt_version' field in the
+	// customers yaml (see cobaltVersionAlias for how the legacy integer
+	// form 0/1 maps onto it).
+	Version SemVer
+	// HashAlgorithm names the IdHasher used to derive metric/report ids from
+	// their names, as set by the 'hash_algorithm' field in the customers
+	// yaml. The empty string means the default (fnv); see HasherByName.
+	HashAlgorithm string
 	ProjectConfig config.CobaltConfig
 }
 
@@ -52,6 +65,11 @@ func parseProjectConfig(y string, c *ProjectConfig) (err error) {
 		return fmt.Errorf("Error while parsing yaml: %v", err)
 	}
 
+	hasher, err := HasherByName(c.HashAlgorithm)
+	if err != nil {
+		return fmt.Errorf("Error in project %v: %v", c.ProjectName, err)
+	}
+
 	// Set of encoding ids. Used to detect duplicates.
 	encodingIds := map[uint32]bool{}
 
@@ -77,9 +95,9 @@ func parseProjectConfig(y string, c *ProjectConfig) (err error) {
 	for _, e := range c.ProjectConfig.MetricDefinitions {
 		e.CustomerId = c.CustomerId
 		e.ProjectId = c.ProjectId
-		e.Id = IdFromName(e.MetricName)
+		e.Id = hasher.Hash(e.MetricName)
 		for _, r := range e.Reports {
-			r.Id = IdFromName(r.ReportName)
+			r.Id = hasher.Hash(r.ReportName)
 		}
 	}
 
@@ -15,6 +15,13 @@
 // Functions in this file parse a yaml string that lists all Cobalt customers
 // and their associated projects. It is used in order to find where the project
 // configs are stored.
+//
+// parseCustomerList, populateProjectList and populateProjectConfig all
+// aggregate every problem they find into a *MultiError, keyed by the
+// fully-qualified path of the offending value (e.g.
+// "customers[3].projects[1].contact"), rather than returning on the first
+// one; that way a reviewer editing a large customers yaml sees every error
+// in one run instead of one per round-trip.
 
 package config_parser
 
@@ -28,46 +35,63 @@ import (
 
 var validNameRegexp = regexp.MustCompile("^[a-zA-Z][_a-zA-Z0-9]{1,81}$")
 
+// hashedProjectIdVersions is the range of cobalt_version for which a
+// project's id is derived from its name via IdFromName, rather than
+// specified explicitly. It excludes 2.0.0 and above so that a future major
+// version can change that derivation without disturbing this one.
+var hashedProjectIdVersions = MustParseVersionRange(">=1.0.0 <2.0.0")
+
 // Parse a list of customers appending all their projects to the ProjectConfig
 // list that was passed in.
-func parseCustomerList(content string, l *[]ProjectConfig) (err error) {
+func parseCustomerList(content string, l *[]ProjectConfig) error {
 	var y []map[string]interface{}
 	if err := yaml.Unmarshal([]byte(content), &y); err != nil {
 		return fmt.Errorf("Error while parsing the yaml for a list of Cobalt customer definitions: %v", err)
 	}
 
+	errs := &MultiError{}
 	customerNames := map[string]bool{}
 	customerIds := map[int]bool{}
 	for i, customer := range y {
+		path := fmt.Sprintf("customers[%d]", i)
+
 		v, ok := customer["customer_name"]
 		if !ok {
-			return fmt.Errorf("customer_name field is missing in entry %v of the customer list.", i)
+			errs.Append(NewFieldError(path+".customer_name", ErrMissingField, "customer_name field is missing in entry %v of the customer list.", i))
+			continue
 		}
 		customerName, ok := v.(string)
 		if !ok {
-			return fmt.Errorf("Customer name '%v' is not a string.", v)
+			errs.Append(NewFieldError(path+".customer_name", nil, "Customer name '%v' is not a string.", v))
+			continue
 		}
 		if !validNameRegexp.MatchString(customerName) {
-			return fmt.Errorf("Customer name '%v' is invalid. Customer names must match the regular expression '%v'", customerName, validNameRegexp)
+			errs.Append(NewFieldError(path+".customer_name", ErrInvalidName, "Customer name '%v' is invalid. Customer names must match the regular expression '%v'", customerName, validNameRegexp))
+			continue
 		}
 		if customerNames[customerName] {
-			return fmt.Errorf("Customer name '%v' repeated. Customer names must be unique.", customerName)
+			errs.Append(NewFieldError(path+".customer_name", ErrDuplicateId, "Customer name '%v' repeated. Customer names must be unique.", customerName))
+			continue
 		}
 		customerNames[customerName] = true
 
 		v, ok = customer["customer_id"]
 		if !ok {
-			return fmt.Errorf("Missing customer id for '%v'.", customerName)
+			errs.Append(NewFieldError(path+".customer_id", ErrMissingField, "Missing customer id for '%v'.", customerName))
+			continue
 		}
 		customerId, ok := v.(int)
 		if !ok {
-			return fmt.Errorf("Customer id '%v' for '%v' is not numeric.", customerId, customerName)
+			errs.Append(NewFieldError(path+".customer_id", nil, "Customer id '%v' for '%v' is not numeric.", v, customerName))
+			continue
 		}
 		if customerId < 0 {
-			return fmt.Errorf("Customer id for '%v' is negative. Customer ids must be positive.", customerName)
+			errs.Append(NewFieldError(path+".customer_id", nil, "Customer id for '%v' is negative. Customer ids must be positive.", customerName))
+			continue
 		}
 		if customerIds[customerId] {
-			return fmt.Errorf("Customer id %v for customer '%v' repeated. Customer names must be unique.", customerId, customerName)
+			errs.Append(NewFieldError(path+".customer_id", ErrDuplicateId, "Customer id %v for customer '%v' repeated. Customer names must be unique.", customerId, customerName))
+			continue
 		}
 		customerIds[customerId] = true
 
@@ -79,12 +103,13 @@ func parseCustomerList(content string, l *[]ProjectConfig) (err error) {
 
 		projectsAsList, ok := projectsAsI.([]interface{})
 		if !ok {
-			fmt.Errorf("Project list for customer %v is invalid. It should be a yaml list.", customerName)
+			errs.Append(NewFieldError(path+".projects", nil, "Project list for customer %v is invalid. It should be a yaml list.", customerName))
+			continue
 		}
 
 		c := []ProjectConfig{}
-		if err := populateProjectList(projectsAsList, &c); err != nil {
-			return fmt.Errorf("Project list for customer %v is invalid: %v", customerName, err)
+		if err := populateProjectList(path+".projects", projectsAsList, &c); err != nil {
+			errs.Append(err)
 		}
 
 		for i := range c {
@@ -94,111 +119,142 @@ func parseCustomerList(content string, l *[]ProjectConfig) (err error) {
 		*l = append(*l, c...)
 	}
 
-	return nil
-
+	return errs.ErrorOrNil()
 }
 
 // populateProjectList populates a list of cobalt projects given in the form of
 // a map as returned by a call to yaml.Unmarshal. For more details, see
 // populateProjectConfig. This function also validates that project names and
-// ids are unique.
-func populateProjectList(y []interface{}, l *[]ProjectConfig) (err error) {
+// ids are unique. path is the fully-qualified location of y, used to prefix
+// the path of any error found within it.
+func populateProjectList(path string, y []interface{}, l *[]ProjectConfig) error {
+	errs := &MultiError{}
 	projectNames := map[string]bool{}
 	projectIds := map[uint32]bool{}
 	for i, v := range y {
+		entryPath := fmt.Sprintf("%s[%d]", path, i)
+
 		m, ok := v.(map[interface{}]interface{})
 		if !ok {
-			return fmt.Errorf("Entry %v in project list is not a yaml map.", i)
+			errs.Append(NewFieldError(entryPath, nil, "Entry %v in project list is not a yaml map.", i))
+			continue
 		}
 		p, err := toStrMap(m)
 		if err != nil {
-			return fmt.Errorf("Entry %v in project list is not valid: %v", i, err)
+			errs.Append(NewFieldError(entryPath, nil, "Entry %v in project list is not valid: %v", i, err))
+			continue
 		}
 		c := ProjectConfig{}
-		if err := populateProjectConfig(p, &c); err != nil {
-			return fmt.Errorf("Error in entry %v in project list: %v", i, err)
+		if err := populateProjectConfig(entryPath, p, &c); err != nil {
+			errs.Append(err)
+			continue
 		}
 
 		if projectNames[c.ProjectName] {
-			return fmt.Errorf("Project name '%v' repeated. Project names must be unique.", c.ProjectName)
+			errs.Append(NewFieldError(entryPath+".name", ErrDuplicateId, "Project name '%v' repeated. Project names must be unique.", c.ProjectName))
+			continue
 		}
 		projectNames[c.ProjectName] = true
 
 		if projectIds[c.ProjectId] {
-			return fmt.Errorf("Project id %v for project %v is repeated. Project ids must be unique.", c.ProjectId, c.ProjectName)
+			errs.Append(NewFieldError(entryPath+".id", ErrDuplicateId, "Project id %v for project %v is repeated. Project ids must be unique.", c.ProjectId, c.ProjectName))
+			continue
 		}
 		projectIds[c.ProjectId] = true
 
 		*l = append(*l, c)
 	}
-	return
+	return errs.ErrorOrNil()
 }
 
 // populateProjectConfig populates a cobalt project given in the form of a map
 // as returned by a call to yaml.Unmarshal. It populates the name, projectId and
 // contact fields of the ProjectConfig it returns. It also validates those
 // values. The project id must be a positive integer. The project must have
-// name, id and contact fields.
-func populateProjectConfig(p map[string]interface{}, c *ProjectConfig) (err error) {
+// name, id and contact fields. path is the fully-qualified location of p,
+// used to prefix the path of any error found within it.
+func populateProjectConfig(path string, p map[string]interface{}, c *ProjectConfig) error {
+	errs := &MultiError{}
+
 	v, ok := p["name"]
 	if !ok {
-		return fmt.Errorf("Missing name in project list.")
-	}
-	c.ProjectName, ok = v.(string)
-	if !ok {
-		return fmt.Errorf("Project name '%v' is not a string.", v)
-	}
-	if !validNameRegexp.MatchString(c.ProjectName) {
-		return fmt.Errorf("Project name '%v' is invalid. Project names must match the regular expression '%v'", c.ProjectName, validNameRegexp)
+		errs.Append(NewFieldError(path+".name", ErrMissingField, "Missing name in project list."))
+	} else if name, isStr := v.(string); !isStr {
+		errs.Append(NewFieldError(path+".name", nil, "Project name '%v' is not a string.", v))
+	} else if !validNameRegexp.MatchString(name) {
+		errs.Append(NewFieldError(path+".name", ErrInvalidName, "Project name '%v' is invalid. Project names must match the regular expression '%v'", name, validNameRegexp))
+	} else {
+		c.ProjectName = name
 	}
 
-	c.CobaltVersion = CobaltVersion0
-	v, ok = p["cobalt_version"]
-	if ok {
-		version, ok := v.(int)
-		if !ok {
-			return fmt.Errorf("Cobalt version '%v' for project %v is not an integer.", v, c.ProjectName)
-		}
-		if version == 0 {
-			c.CobaltVersion = CobaltVersion0
-		} else if version == 1 {
-			c.CobaltVersion = CobaltVersion1
-		} else {
-			return fmt.Errorf("Version '%v' for project %v is not '1' or '0'.", version, c.ProjectName)
+	if v, ok = p["cobalt_version"]; ok {
+		switch version := v.(type) {
+		case int:
+			if ver, err := cobaltVersionAlias(version); err != nil {
+				errs.Append(NewFieldError(path+".cobalt_version", nil, "%v Project: %v.", err, c.ProjectName))
+			} else {
+				c.Version = ver
+			}
+		case string:
+			if ver, err := ParseSemVer(version); err != nil {
+				errs.Append(NewFieldError(path+".cobalt_version", nil, "%v Project: %v.", err, c.ProjectName))
+			} else {
+				c.Version = ver
+			}
+		default:
+			errs.Append(NewFieldError(path+".cobalt_version", nil, "Cobalt version '%v' for project %v is neither an integer nor a semver string.", v, c.ProjectName))
 		}
 	}
 
+	if c.Version.Major >= 1 {
+		c.CobaltVersion = CobaltVersion1
+	} else {
+		c.CobaltVersion = CobaltVersion0
+	}
+
 	if c.CobaltVersion == CobaltVersion1 {
-		_, ok = p["id"]
-		if ok {
-			return fmt.Errorf("Project %v is using version 1.0. Version 1.0 projects may not specify an id.", c.ProjectName)
+		if _, ok = p["id"]; ok {
+			errs.Append(NewFieldError(path+".id", nil, "Project %v is using version 1.0. Version 1.0 projects may not specify an id.", c.ProjectName))
+		}
+		if hashedProjectIdVersions.Matches(c.Version) {
+			c.ProjectId = IdFromName(c.ProjectName)
+		} else {
+			errs.Append(NewFieldError(path+".cobalt_version", nil, "Cobalt version %v for project %v has no defined project id derivation.", c.Version, c.ProjectName))
 		}
-		c.ProjectId = IdFromName(c.ProjectName)
 	} else {
 		v, ok = p["id"]
 		if !ok {
-			return fmt.Errorf("Missing id for project %v.", c.ProjectName)
-		}
-		projectId, ok := v.(int)
-		if !ok {
-			return fmt.Errorf("Id '%v' for project %v is not an integer.", v, c.ProjectName)
-		}
-		if projectId <= 0 {
-			return fmt.Errorf("Id for project %v is not a positive integer.", c.ProjectName)
+			errs.Append(NewFieldError(path+".id", ErrMissingField, "Missing id for project %v.", c.ProjectName))
+		} else if projectId, isInt := v.(int); !isInt {
+			errs.Append(NewFieldError(path+".id", nil, "Id '%v' for project %v is not an integer.", v, c.ProjectName))
+		} else if projectId <= 0 {
+			errs.Append(NewFieldError(path+".id", nil, "Id for project %v is not a positive integer.", c.ProjectName))
+		} else {
+			c.ProjectId = uint32(projectId)
 		}
-		c.ProjectId = uint32(projectId)
 	}
 
 	v, ok = p["contact"]
 	if !ok {
-		return fmt.Errorf("Missing contact for project %v.", c.ProjectName)
+		errs.Append(NewFieldError(path+".contact", ErrMissingField, "Missing contact for project %v.", c.ProjectName))
+	} else if contact, isStr := v.(string); !isStr {
+		errs.Append(NewFieldError(path+".contact", nil, "Contact '%v' for project %v is not a string.", v, c.ProjectName))
+	} else {
+		c.Contact = contact
 	}
-	c.Contact, ok = v.(string)
-	if !ok {
-		return fmt.Errorf("Contact '%v' for project %v is not a string.", v, c.ProjectName)
+
+	if v, ok = p["hash_algorithm"]; ok {
+		hashAlgorithm, isStr := v.(string)
+		if !isStr {
+			errs.Append(NewFieldError(path+".hash_algorithm", nil, "hash_algorithm '%v' for project %v is not a string.", v, c.ProjectName))
+		} else if _, err := HasherByName(hashAlgorithm); err != nil {
+			errs.Append(NewFieldError(path+".hash_algorithm", nil, "Invalid hash_algorithm for project %v: %v", c.ProjectName, err))
+		} else {
+			c.HashAlgorithm = hashAlgorithm
+		}
 	}
 
-	return nil
+	return errs.ErrorOrNil()
 }
 
 func toStrMap(i map[interface{}]interface{}) (o map[string]interface{}, err error) {
@@ -0,0 +1,148 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"errors"
+	"testing"
+)
+
+// Tests that parseCustomerList populates names, ids and contacts correctly
+// for a well formed customer list.
+func TestParseCustomerListValid(t *testing.T) {
+	l := []ProjectConfig{}
+	if err := parseCustomerList(customersYaml, &l); err != nil {
+		t.Fatalf("Unexpected error parsing a valid customer list: %v", err)
+	}
+
+	if len(l) != 3 {
+		t.Fatalf("Expected 3 projects, got %v", l)
+	}
+
+	if l[0].CustomerName != "fuchsia" || l[0].CustomerId != 1 || l[0].ProjectName != "ledger" || l[0].ProjectId != 100 || l[0].Contact != "bob" {
+		t.Errorf("Unexpected first project: %+v", l[0])
+	}
+}
+
+// Tests that parseCustomerList reports every problem it finds across
+// multiple customers and projects in one pass, instead of stopping at the
+// first one, and that the resulting error still satisfies errors.Is for the
+// sentinel kind of each individual failure.
+func TestParseCustomerListAggregatesErrors(t *testing.T) {
+	const badYaml = `
+- customer_name: 1bad
+  customer_id: 1
+  projects:
+    - name: a_project
+      id: 100
+      contact: bob
+- customer_name: good_customer
+  customer_id: 2
+  projects:
+    - name: missing_contact
+      id: 200
+`
+	l := []ProjectConfig{}
+	err := parseCustomerList(badYaml, &l)
+	if err == nil {
+		t.Fatal("Expected an error for a customer list with multiple problems.")
+	}
+
+	if !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Expected errors.Is to find ErrInvalidName in %v", err)
+	}
+	if !errors.Is(err, ErrMissingField) {
+		t.Errorf("Expected errors.Is to find ErrMissingField in %v", err)
+	}
+	if errors.Is(err, ErrDuplicateId) {
+		t.Error("errors.Is unexpectedly matched ErrDuplicateId.")
+	}
+}
+
+// Tests that cobalt_version accepts a semver string in addition to the
+// legacy integer form, and that the legacy integers still alias to the
+// expected semver (see cobaltVersionAlias).
+func TestParseCustomerListCobaltVersionSemVer(t *testing.T) {
+	const yaml = `
+- customer_name: a_customer
+  customer_id: 1
+  projects:
+    - name: legacy_v0
+      id: 100
+      contact: bob
+    - name: legacy_v1
+      cobalt_version: 1
+      contact: bob
+    - name: semver_project
+      cobalt_version: "1.2.0"
+      contact: bob
+`
+	l := []ProjectConfig{}
+	if err := parseCustomerList(yaml, &l); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	byName := map[string]ProjectConfig{}
+	for _, p := range l {
+		byName[p.ProjectName] = p
+	}
+
+	if v := byName["legacy_v0"].Version; v != (SemVer{}) {
+		t.Errorf("Expected legacy_v0 to alias to 0.0.0, got %v", v)
+	}
+	if v := byName["legacy_v1"].Version; v != (SemVer{Major: 1, Minor: 0, Patch: 0}) {
+		t.Errorf("Expected legacy_v1 to alias to 1.0.0, got %v", v)
+	}
+	if v := byName["semver_project"].Version; v != (SemVer{Major: 1, Minor: 2, Patch: 0}) {
+		t.Errorf("Expected semver_project to parse to 1.2.0, got %v", v)
+	}
+	if byName["semver_project"].CobaltVersion != CobaltVersion1 {
+		t.Errorf("Expected semver_project's derived CobaltVersion to be CobaltVersion1, got %v", byName["semver_project"].CobaltVersion)
+	}
+}
+
+// Tests that a single project with several invalid fields contributes one
+// FieldError per field, all reachable from the top-level error returned by
+// parseCustomerList.
+func TestParseCustomerListAggregatesWithinOneProject(t *testing.T) {
+	const badYaml = `
+- customer_name: a_customer
+  customer_id: 1
+  projects:
+    - name: 1bad
+      contact: 5
+`
+	l := []ProjectConfig{}
+	err := parseCustomerList(badYaml, &l)
+	if err == nil {
+		t.Fatal("Expected an error for a project with an invalid name and a non-string contact.")
+	}
+
+	if !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Expected errors.Is to find ErrInvalidName in %v", err)
+	}
+	if !errors.Is(err, ErrMissingField) {
+		t.Errorf("Expected errors.Is to find ErrMissingField in %v", err)
+	}
+}
+
+// Tests that a cobalt_version of 2.0.0 or above, which falls outside
+// hashedProjectIdVersions, is rejected rather than silently leaving
+// ProjectId at its zero value.
+func TestParseCustomerListRejectsUnhandledCobaltVersion(t *testing.T) {
+	const yaml = `
+- customer_name: a_customer
+  customer_id: 1
+  projects:
+    - name: future_project
+      cobalt_version: "2.0.0"
+      contact: bob
+`
+	l := []ProjectConfig{}
+	err := parseCustomerList(yaml, &l)
+	if err == nil {
+		t.Fatal("Expected an error for a project with cobalt_version 2.0.0.")
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file lets a customer registry be split across many files instead of
+// one monolithic customers yaml, so a team can own
+// config/customers.d/<customer>.yaml without touching a shared document.
+// Each file is either a full customer list (the same shape parseCustomerList
+// accepts) or a project-only fragment: a single yaml map with a
+// customer_name/customer_id header and the project's own fields (name, id,
+// contact, ...) alongside it, with no 'projects' nesting.
+
+package config_parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "github.com/go-yaml/yaml"
+)
+
+// registryEntry is one ProjectConfig together with the path of the file it
+// was read from, so mergeRegistryEntries can name both files in a conflict.
+type registryEntry struct {
+	config ProjectConfig
+	source string
+}
+
+// LoadCustomerRegistry walks each of roots on the real filesystem (a
+// trailing slash is accepted and ignored), reads every *.yaml file under it
+// in lexical order, and merges them into a single customer registry the
+// same way LoadCustomerRegistryFS does.
+func LoadCustomerRegistry(roots ...string) ([]ProjectConfig, error) {
+	var all []registryEntry
+	for _, root := range roots {
+		root = normalizeRoot(root)
+		entries, err := walkYamlFiles(os.DirFS(root), ".")
+		if err != nil {
+			return nil, err
+		}
+		for i := range entries {
+			entries[i].source = filepath.Join(root, entries[i].source)
+		}
+		all = append(all, entries...)
+	}
+	return mergeRegistryEntries(all)
+}
+
+// LoadCustomerRegistryFS is the fs.FS-backed implementation behind
+// LoadCustomerRegistry, exposed so callers and tests can back it with an
+// embed.FS or an in-memory fstest.MapFS instead of the real filesystem.
+// Unlike LoadCustomerRegistry, each root here is a path within fsys, not an
+// independent filesystem, so multiple roots may share one fsys (e.g. an
+// embed.FS holding several environments' customers.d directories).
+func LoadCustomerRegistryFS(fsys fs.FS, roots ...string) ([]ProjectConfig, error) {
+	var all []registryEntry
+	for _, root := range roots {
+		entries, err := walkYamlFiles(fsys, normalizeRoot(root))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, entries...)
+	}
+	return mergeRegistryEntries(all)
+}
+
+// normalizeRoot strips a trailing slash from root, and maps the empty
+// string to ".", the path fs.WalkDir expects for "the whole tree".
+func normalizeRoot(root string) string {
+	root = strings.TrimRight(root, "/")
+	if root == "" {
+		return "."
+	}
+	return root
+}
+
+// walkYamlFiles reads every *.yaml file under root in fsys, in the lexical
+// order fs.WalkDir visits them in, and parses each into the ProjectConfigs
+// it defines.
+func walkYamlFiles(fsys fs.FS, root string) (entries []registryEntry, err error) {
+	err = fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("Error walking '%v': %v", path, err)
+		}
+		if d.IsDir() || filepath.Ext(path) != ".yaml" {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return fmt.Errorf("Error reading '%v': %v", path, err)
+		}
+
+		configs, err := parseYamlFragment(string(content), path)
+		if err != nil {
+			return err
+		}
+		for _, c := range configs {
+			entries = append(entries, registryEntry{config: c, source: path})
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// parseYamlFragment parses the content of one customer registry file, which
+// is either a full customer list (a yaml list, handled by parseCustomerList
+// directly) or a single project fragment (a yaml map carrying a
+// customer_name/customer_id header plus that project's own fields).
+// sourceFile is used only to make error messages point at the right file.
+func parseYamlFragment(content string, sourceFile string) ([]ProjectConfig, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal([]byte(content), &raw); err != nil {
+		return nil, fmt.Errorf("Error parsing yaml in '%v': %v", sourceFile, err)
+	}
+
+	switch v := raw.(type) {
+	case []interface{}:
+		l := []ProjectConfig{}
+		if err := parseCustomerList(content, &l); err != nil {
+			return nil, fmt.Errorf("Error in '%v': %v", sourceFile, err)
+		}
+		return l, nil
+	case map[interface{}]interface{}:
+		return parseProjectFragment(v, sourceFile)
+	default:
+		return nil, fmt.Errorf("'%v' is neither a customer list (a yaml list) nor a project fragment (a yaml map).", sourceFile)
+	}
+}
+
+// parseProjectFragment turns a project-only fragment into the one-customer,
+// one-project customer list parseCustomerList expects, by lifting its
+// customer_name/customer_id header out into a synthetic customer entry
+// wrapping the rest of the fragment as that customer's sole project.
+func parseProjectFragment(m map[interface{}]interface{}, sourceFile string) ([]ProjectConfig, error) {
+	strMap, err := toStrMap(m)
+	if err != nil {
+		return nil, fmt.Errorf("Error in '%v': %v", sourceFile, err)
+	}
+
+	if _, ok := strMap["customer_name"]; !ok {
+		return nil, fmt.Errorf("'%v' is a project fragment but is missing a customer_name header.", sourceFile)
+	}
+	if _, ok := strMap["customer_id"]; !ok {
+		return nil, fmt.Errorf("'%v' is a project fragment but is missing a customer_id header.", sourceFile)
+	}
+
+	project := map[string]interface{}{}
+	for k, v := range strMap {
+		if k == "customer_name" || k == "customer_id" {
+			continue
+		}
+		project[k] = v
+	}
+
+	customer := toJSONValue(map[string]interface{}{
+		"customer_name": strMap["customer_name"],
+		"customer_id":   strMap["customer_id"],
+		"projects":      []interface{}{project},
+	})
+
+	encoded, err := json.Marshal([]interface{}{customer})
+	if err != nil {
+		return nil, fmt.Errorf("Error re-encoding fragment '%v': %v", sourceFile, err)
+	}
+
+	l := []ProjectConfig{}
+	if err := parseCustomerList(string(encoded), &l); err != nil {
+		return nil, fmt.Errorf("Error in '%v': %v", sourceFile, err)
+	}
+	return l, nil
+}
+
+// mergeRegistryEntries combines every registryEntry read across every root
+// into a single customer registry, rejecting a customer_id reused for a
+// different customer_name, a project name repeated within a customer, or a
+// project_id repeated within a customer, and naming both source files when
+// any of those happens.
+func mergeRegistryEntries(entries []registryEntry) ([]ProjectConfig, error) {
+	customerNameByCustomerId := map[uint32]string{}
+	customerSourceByCustomerId := map[uint32]string{}
+	projectSourceByKey := map[string]string{}
+	projectIdSourceByKey := map[string]string{}
+
+	configs := make([]ProjectConfig, 0, len(entries))
+	for _, e := range entries {
+		c := e.config
+
+		if name, ok := customerNameByCustomerId[c.CustomerId]; ok {
+			if name != c.CustomerName {
+				return nil, fmt.Errorf("customer_id %v is claimed by both '%v' (in %v) and '%v' (in %v).", c.CustomerId, name, customerSourceByCustomerId[c.CustomerId], c.CustomerName, e.source)
+			}
+		} else {
+			customerNameByCustomerId[c.CustomerId] = c.CustomerName
+			customerSourceByCustomerId[c.CustomerId] = e.source
+		}
+
+		key := fmt.Sprintf("%d|%s", c.CustomerId, c.ProjectName)
+		if prevSource, ok := projectSourceByKey[key]; ok {
+			return nil, fmt.Errorf("project '%v' for customer '%v' is defined in both %v and %v.", c.ProjectName, c.CustomerName, prevSource, e.source)
+		}
+		projectSourceByKey[key] = e.source
+
+		idKey := fmt.Sprintf("%d|%d", c.CustomerId, c.ProjectId)
+		if prevSource, ok := projectIdSourceByKey[idKey]; ok {
+			return nil, fmt.Errorf("project_id %v for customer '%v' is claimed by both %v and %v.", c.ProjectId, c.CustomerName, prevSource, e.source)
+		}
+		projectIdSourceByKey[idKey] = e.source
+
+		configs = append(configs, c)
+	}
+	return configs, nil
+}
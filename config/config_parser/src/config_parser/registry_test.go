@@ -0,0 +1,150 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadCustomerRegistryFSMergesFullListsAndFragments(t *testing.T) {
+	fsys := fstest.MapFS{
+		"customers.d/acme.yaml": &fstest.MapFile{Data: []byte(`
+- customer_name: acme
+  customer_id: 1
+  projects:
+    - name: widgets
+      id: 100
+      contact: bob
+`)},
+		"customers.d/acme_gadgets.yaml": &fstest.MapFile{Data: []byte(`
+customer_name: acme
+customer_id: 1
+name: gadgets
+id: 101
+contact: bob
+`)},
+	}
+
+	l, err := LoadCustomerRegistryFS(fsys, "customers.d")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(l) != 2 {
+		t.Fatalf("Expected 2 projects, got %v", l)
+	}
+
+	byName := map[string]ProjectConfig{}
+	for _, p := range l {
+		byName[p.ProjectName] = p
+	}
+	if byName["widgets"].CustomerId != 1 || byName["gadgets"].CustomerId != 1 {
+		t.Errorf("Expected both projects to belong to customer_id 1, got %+v", l)
+	}
+}
+
+func TestLoadCustomerRegistryFSDuplicateCustomerIdConflict(t *testing.T) {
+	fsys := fstest.MapFS{
+		"customers.d/a.yaml": &fstest.MapFile{Data: []byte(`
+customer_name: acme
+customer_id: 1
+name: widgets
+id: 100
+contact: bob
+`)},
+		"customers.d/b.yaml": &fstest.MapFile{Data: []byte(`
+customer_name: other_corp
+customer_id: 1
+name: gizmos
+id: 100
+contact: bob
+`)},
+	}
+
+	_, err := LoadCustomerRegistryFS(fsys, "customers.d")
+	if err == nil {
+		t.Fatal("Expected an error for two customer names sharing one customer_id.")
+	}
+	if !strings.Contains(err.Error(), "a.yaml") || !strings.Contains(err.Error(), "b.yaml") {
+		t.Errorf("Expected the error to name both source files, got: %v", err)
+	}
+}
+
+func TestLoadCustomerRegistryFSDuplicateProjectNameConflict(t *testing.T) {
+	fsys := fstest.MapFS{
+		"customers.d/a.yaml": &fstest.MapFile{Data: []byte(`
+customer_name: acme
+customer_id: 1
+name: widgets
+id: 100
+contact: bob
+`)},
+		"customers.d/b.yaml": &fstest.MapFile{Data: []byte(`
+customer_name: acme
+customer_id: 1
+name: widgets
+id: 101
+contact: alice
+`)},
+	}
+
+	_, err := LoadCustomerRegistryFS(fsys, "customers.d")
+	if err == nil {
+		t.Fatal("Expected an error for a project name repeated within a customer across files.")
+	}
+	if !strings.Contains(err.Error(), "a.yaml") || !strings.Contains(err.Error(), "b.yaml") {
+		t.Errorf("Expected the error to name both source files, got: %v", err)
+	}
+}
+
+// Tests that two differently named projects for the same customer that
+// share an id across split files are rejected, not just a repeated project
+// name: the monolithic customer list path already catches this via its
+// projectIds map, and splitting a customer across files must not lose that
+// protection.
+func TestLoadCustomerRegistryFSDuplicateProjectIdConflict(t *testing.T) {
+	fsys := fstest.MapFS{
+		"customers.d/a.yaml": &fstest.MapFile{Data: []byte(`
+customer_name: acme
+customer_id: 1
+name: widgets
+id: 100
+contact: bob
+`)},
+		"customers.d/b.yaml": &fstest.MapFile{Data: []byte(`
+customer_name: acme
+customer_id: 1
+name: gadgets
+id: 100
+contact: alice
+`)},
+	}
+
+	_, err := LoadCustomerRegistryFS(fsys, "customers.d")
+	if err == nil {
+		t.Fatal("Expected an error for a project id repeated within a customer across files.")
+	}
+	if !strings.Contains(err.Error(), "a.yaml") || !strings.Contains(err.Error(), "b.yaml") {
+		t.Errorf("Expected the error to name both source files, got: %v", err)
+	}
+}
+
+func TestLoadCustomerRegistryFSFragmentMissingHeader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"customers.d/bad.yaml": &fstest.MapFile{Data: []byte(`
+customer_id: 1
+name: widgets
+id: 100
+contact: bob
+`)},
+	}
+
+	_, err := LoadCustomerRegistryFS(fsys, "customers.d")
+	if err == nil {
+		t.Fatal("Expected an error for a fragment missing its customer_name header.")
+	}
+}
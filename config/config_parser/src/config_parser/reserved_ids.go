@@ -0,0 +1,104 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains ReservedIdsRegistry, a persisted record of every metric
+// and report id a project has ever used, keyed by the name that produced it.
+// Since ids are derived from names via an IdHasher rather than assigned
+// explicitly, a rename or deletion followed by a new, unrelated metric/report
+// that happens to hash to the same id would otherwise silently reuse an id
+// that historical data was tagged with. The registry lets validation catch
+// that before it ships.
+
+package config_parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	yaml "github.com/go-yaml/yaml"
+)
+
+// ReservedIdsRegistry records the ids a project's metrics and reports have
+// ever hashed to, so that a later rename or deletion can't silently cause a
+// new metric/report to reuse an id that still has historical data attached.
+type ReservedIdsRegistry struct {
+	Metrics map[uint32]string `yaml:"metrics"`
+	Reports map[uint32]string `yaml:"reports"`
+}
+
+// ReadReservedIdsRegistry reads a ReservedIdsRegistry from path. A missing
+// file is treated as an empty registry, since every project starts without
+// one.
+func ReadReservedIdsRegistry(path string) (ReservedIdsRegistry, error) {
+	r := ReservedIdsRegistry{Metrics: map[uint32]string{}, Reports: map[uint32]string{}}
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return r, fmt.Errorf("Error reading reserved ids registry '%v': %v", path, err)
+	}
+
+	if err := yaml.Unmarshal(content, &r); err != nil {
+		return r, fmt.Errorf("Error parsing reserved ids registry '%v': %v", path, err)
+	}
+
+	if r.Metrics == nil {
+		r.Metrics = map[uint32]string{}
+	}
+	if r.Reports == nil {
+		r.Reports = map[uint32]string{}
+	}
+
+	return r, nil
+}
+
+// WriteReservedIdsRegistry writes r to path as yaml, creating path's parent
+// directory if it does not already exist.
+func WriteReservedIdsRegistry(path string, r ReservedIdsRegistry) error {
+	content, err := yaml.Marshal(&r)
+	if err != nil {
+		return fmt.Errorf("Error serializing reserved ids registry: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("Error creating directory for reserved ids registry '%v': %v", path, err)
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("Error writing reserved ids registry '%v': %v", path, err)
+	}
+	return nil
+}
+
+// ReservedIdsPathForProject returns the path of the reserved ids registry
+// file for one customer/project, given root (the --reserved_ids_file
+// directory). Each project gets its own file, keyed the same way
+// GetConfigFilesListByProject keys a project's yaml files, so that two
+// unrelated projects whose metric/report names happen to hash to the same
+// id don't spuriously conflict with each other's registry.
+func ReservedIdsPathForProject(root string, customerName string, projectName string) string {
+	return filepath.Join(root, customerName, projectName+".yaml")
+}
+
+// ReserveMetric checks id against any metric id already reserved under a
+// different name and, if there is no conflict, reserves it for name.
+func (r *ReservedIdsRegistry) ReserveMetric(id uint32, name string) error {
+	return reserve(r.Metrics, id, name, "metric")
+}
+
+// ReserveReport checks id against any report id already reserved under a
+// different name and, if there is no conflict, reserves it for name.
+func (r *ReservedIdsRegistry) ReserveReport(id uint32, name string) error {
+	return reserve(r.Reports, id, name, "report")
+}
+
+func reserve(ids map[uint32]string, id uint32, name, kind string) error {
+	if existing, ok := ids[id]; ok && existing != name {
+		return fmt.Errorf("%s '%v' hashes to id %v, which is reserved for '%v'. Historical data was recorded under that id; it may not be reused.", kind, name, id, existing)
+	}
+	ids[id] = name
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestReadReservedIdsRegistryMissingFile(t *testing.T) {
+	r, err := ReadReservedIdsRegistry("/does/not/exist/reserved_ids.yaml")
+	if err != nil {
+		t.Fatalf("Error reading a missing reserved ids registry: %v", err)
+	}
+
+	if len(r.Metrics) != 0 || len(r.Reports) != 0 {
+		t.Errorf("Expected an empty registry for a missing file, got %+v", r)
+	}
+
+	if err := r.ReserveMetric(1, "a_metric"); err != nil {
+		t.Errorf("ReserveMetric failed on an empty registry: %v", err)
+	}
+}
+
+func TestReservedIdsRegistryRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobalt_config_reserved_ids_test")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := ReservedIdsRegistry{
+		Metrics: map[uint32]string{1: "a_metric"},
+		Reports: map[uint32]string{2: "a_report"},
+	}
+
+	registryPath := path.Join(dir, "reserved_ids.yaml")
+	if err := WriteReservedIdsRegistry(registryPath, r); err != nil {
+		t.Fatalf("Error writing reserved ids registry: %v", err)
+	}
+
+	readR, err := ReadReservedIdsRegistry(registryPath)
+	if err != nil {
+		t.Fatalf("Error reading reserved ids registry: %v", err)
+	}
+
+	if readR.Metrics[1] != "a_metric" {
+		t.Errorf("Unexpected metrics after round trip: %+v", readR.Metrics)
+	}
+
+	if readR.Reports[2] != "a_report" {
+		t.Errorf("Unexpected reports after round trip: %+v", readR.Reports)
+	}
+}
+
+func TestReserveMetric(t *testing.T) {
+	r := ReservedIdsRegistry{Metrics: map[uint32]string{}, Reports: map[uint32]string{}}
+
+	if err := r.ReserveMetric(1, "a_metric"); err != nil {
+		t.Errorf("ReserveMetric failed reserving a new id: %v", err)
+	}
+
+	if err := r.ReserveMetric(1, "a_metric"); err != nil {
+		t.Errorf("ReserveMetric failed re-reserving the same name: %v", err)
+	}
+
+	if err := r.ReserveMetric(1, "a_different_metric"); err == nil {
+		t.Errorf("ReserveMetric did not catch an id collision between two names.")
+	}
+}
+
+func TestReservedIdsPathForProject(t *testing.T) {
+	got := ReservedIdsPathForProject("/reserved_ids", "acme", "widgets")
+	want := path.Join("/reserved_ids", "acme", "widgets.yaml")
+	if got != want {
+		t.Errorf("ReservedIdsPathForProject(...) = %v, want %v", got, want)
+	}
+}
+
+func TestWriteReservedIdsRegistryCreatesParentDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobalt_config_reserved_ids_test")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	registryPath := ReservedIdsPathForProject(dir, "acme", "widgets")
+	r := ReservedIdsRegistry{Metrics: map[uint32]string{1: "a_metric"}, Reports: map[uint32]string{}}
+	if err := WriteReservedIdsRegistry(registryPath, r); err != nil {
+		t.Fatalf("Error writing reserved ids registry: %v", err)
+	}
+
+	readR, err := ReadReservedIdsRegistry(registryPath)
+	if err != nil {
+		t.Fatalf("Error reading reserved ids registry: %v", err)
+	}
+	if readR.Metrics[1] != "a_metric" {
+		t.Errorf("Unexpected metrics after round trip: %+v", readR.Metrics)
+	}
+}
+
+func TestReserveReport(t *testing.T) {
+	r := ReservedIdsRegistry{Metrics: map[uint32]string{}, Reports: map[uint32]string{}}
+
+	if err := r.ReserveReport(1, "a_report"); err != nil {
+		t.Errorf("ReserveReport failed reserving a new id: %v", err)
+	}
+
+	if err := r.ReserveReport(1, "a_different_report"); err == nil {
+		t.Errorf("ReserveReport did not catch an id collision between two names.")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains a RustOutputFactory that behaves like CppOutputFactory
+// and DartOutputFactory, but emits a Rust module of typed constants instead
+// of a serialized proto, so Rust clients can refer to metric/report ids and
+// event codes by name without parsing the binary config at runtime.
+
+package config_parser
+
+import (
+	"bytes"
+	"config"
+	"fmt"
+	"sort"
+)
+
+// RustOutputFactory returns an OutputFormatter that writes a Rust source file
+// declaring `pub const` u32 ids for every metric and report in the config,
+// plus a `pub enum` of the event codes for every metric that declares any
+// event_types, whatever its metric_type.
+// modName is used as the name of the wrapping `pub mod`.
+func RustOutputFactory(modName string) OutputFormatter {
+	return func(c *config.CobaltConfig) ([]byte, error) {
+		var b bytes.Buffer
+
+		fmt.Fprintf(&b, "// Generated by the Cobalt config_parser. DO NOT EDIT.\n\n")
+		fmt.Fprintf(&b, "pub mod %s {\n", modName)
+
+		for _, m := range c.MetricDefinitions {
+			fmt.Fprintf(&b, "    pub const %s: u32 = %d;\n", rustConstName(m.MetricName), m.Id)
+			for _, r := range m.Reports {
+				fmt.Fprintf(&b, "    pub const %s: u32 = %d;\n", rustConstName(m.MetricName+"_"+r.ReportName), r.Id)
+			}
+
+			if len(m.EventTypes) > 0 {
+				if err := writeRustEventCodeEnum(&b, m); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		fmt.Fprintf(&b, "}\n")
+
+		return b.Bytes(), nil
+	}
+}
+
+func writeRustEventCodeEnum(b *bytes.Buffer, m *config.MetricDefinition) error {
+	codes := make([]uint32, 0, len(m.EventTypes))
+	for code := range m.EventTypes {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	fmt.Fprintf(b, "    #[derive(Clone, Copy, Debug, Eq, PartialEq)]\n")
+	fmt.Fprintf(b, "    pub enum %sEventCode {\n", rustTypeName(m.MetricName))
+	for _, code := range codes {
+		fmt.Fprintf(b, "        %s = %d,\n", rustTypeName(m.EventTypes[code]), code)
+	}
+	fmt.Fprintf(b, "    }\n")
+
+	return nil
+}
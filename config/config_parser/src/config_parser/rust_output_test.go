@@ -0,0 +1,44 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"config"
+	"strings"
+	"testing"
+)
+
+func TestRustOutputFactory(t *testing.T) {
+	c := config.CobaltConfig{
+		MetricDefinitions: []*config.MetricDefinition{
+			{
+				MetricName: "the_metric_name",
+				Id:         42,
+				EventTypes: map[uint32]string{0: "an_event"},
+				Reports: []*config.ReportDefinition{
+					{ReportName: "the_report", Id: 7},
+				},
+			},
+		},
+	}
+
+	b, err := RustOutputFactory("config")(&c)
+	if err != nil {
+		t.Fatalf("Error generating Rust output: %v", err)
+	}
+	out := string(b)
+
+	for _, want := range []string{
+		"pub mod config {",
+		"pub const THE_METRIC_NAME: u32 = 42;",
+		"pub const THE_METRIC_NAME_THE_REPORT: u32 = 7;",
+		"pub enum TheMetricNameEventCode {",
+		"AnEvent = 0,",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected generated Rust to contain %q, got:\n%v", want, out)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file implements just enough of semver (https://semver.org) for
+// cobalt_version: parsing a "major.minor.patch" string, comparing two
+// versions, and matching a version against a small space-separated
+// constraint expression like ">=1.1.0 <2.0.0". It intentionally does not
+// support pre-release or build metadata tags; cobalt_version has never used
+// them and config_validator's version-gated rules only ever need to compare
+// major.minor.patch.
+
+package config_parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SemVer is a parsed "major.minor.patch" version.
+type SemVer struct {
+	Major, Minor, Patch int
+}
+
+func (v SemVer) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 as v is less than, equal to, or greater than o.
+func (v SemVer) Compare(o SemVer) int {
+	for _, pair := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// ParseSemVer parses a "major[.minor[.patch]]" string. Missing components
+// default to 0, so "1" parses the same as "1.0.0".
+func ParseSemVer(s string) (SemVer, error) {
+	parts := strings.SplitN(s, ".", 3)
+	if len(parts) == 0 || parts[0] == "" {
+		return SemVer{}, fmt.Errorf("'%v' is not a valid semver string.", s)
+	}
+
+	nums := [3]int{}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return SemVer{}, fmt.Errorf("'%v' is not a valid semver string: component '%v' is not a non-negative integer.", s, p)
+		}
+		nums[i] = n
+	}
+
+	return SemVer{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+// cobaltVersionAlias maps the legacy integer form of cobalt_version to the
+// semver it is equivalent to, for backwards compatibility: 0 means "any 0.x
+// release", 1 means exactly 1.0.0.
+func cobaltVersionAlias(legacy int) (SemVer, error) {
+	switch legacy {
+	case 0:
+		return SemVer{Major: 0}, nil
+	case 1:
+		return SemVer{Major: 1, Minor: 0, Patch: 0}, nil
+	default:
+		return SemVer{}, fmt.Errorf("Version '%v' is not '1' or '0'.", legacy)
+	}
+}
+
+// VersionRange is a set of semver constraints, all of which a version must
+// satisfy; comparators are one of ">=", "<=", ">", "<", "=".
+type VersionRange struct {
+	constraints []versionConstraint
+}
+
+type versionConstraint struct {
+	op  string
+	ver SemVer
+}
+
+func (r VersionRange) String() string {
+	parts := make([]string, len(r.constraints))
+	for i, c := range r.constraints {
+		parts[i] = c.op + c.ver.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseVersionRange parses a space-separated list of constraints such as
+// ">=1.1.0 <2.0.0". An empty string parses to a range that matches every
+// version.
+func ParseVersionRange(s string) (VersionRange, error) {
+	var r VersionRange
+	for _, field := range strings.Fields(s) {
+		op := ""
+		for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(field, candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return VersionRange{}, fmt.Errorf("'%v' does not start with a comparator (one of >=, <=, >, <, =).", field)
+		}
+
+		ver, err := ParseSemVer(strings.TrimPrefix(field, op))
+		if err != nil {
+			return VersionRange{}, err
+		}
+		r.constraints = append(r.constraints, versionConstraint{op: op, ver: ver})
+	}
+	return r, nil
+}
+
+// MustParseVersionRange is like ParseVersionRange but panics on error, for
+// constructing package-level VersionRange values from a literal constant,
+// the same way regexp.MustCompile is used for package-level regular
+// expressions.
+func MustParseVersionRange(s string) VersionRange {
+	r, err := ParseVersionRange(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Matches reports whether v satisfies every constraint in r.
+func (r VersionRange) Matches(v SemVer) bool {
+	for _, c := range r.constraints {
+		cmp := v.Compare(c.ver)
+		switch c.op {
+		case ">=":
+			if cmp < 0 {
+				return false
+			}
+		case "<=":
+			if cmp > 0 {
+				return false
+			}
+		case ">":
+			if cmp <= 0 {
+				return false
+			}
+		case "<":
+			if cmp >= 0 {
+				return false
+			}
+		case "=":
+			if cmp != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import "testing"
+
+func TestParseSemVer(t *testing.T) {
+	v, err := ParseSemVer("1.2.3")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing '1.2.3': %v", err)
+	}
+	if v != (SemVer{Major: 1, Minor: 2, Patch: 3}) {
+		t.Errorf("Unexpected parse result: %+v", v)
+	}
+
+	if _, err := ParseSemVer("not-a-version"); err == nil {
+		t.Error("Expected an error parsing an invalid semver string.")
+	}
+}
+
+func TestSemVerCompare(t *testing.T) {
+	cases := []struct {
+		a, b SemVer
+		want int
+	}{
+		{SemVer{1, 0, 0}, SemVer{1, 0, 0}, 0},
+		{SemVer{1, 0, 0}, SemVer{1, 1, 0}, -1},
+		{SemVer{2, 0, 0}, SemVer{1, 9, 9}, 1},
+		{SemVer{1, 2, 3}, SemVer{1, 2, 4}, -1},
+	}
+	for _, c := range cases {
+		if got := c.a.Compare(c.b); got != c.want {
+			t.Errorf("%v.Compare(%v) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestVersionRangeMatches(t *testing.T) {
+	r, err := ParseVersionRange(">=1.1.0 <2.0.0")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing version range: %v", err)
+	}
+
+	cases := []struct {
+		v    SemVer
+		want bool
+	}{
+		{SemVer{1, 1, 0}, true},
+		{SemVer{1, 5, 2}, true},
+		{SemVer{1, 0, 9}, false},
+		{SemVer{2, 0, 0}, false},
+	}
+	for _, c := range cases {
+		if got := r.Matches(c.v); got != c.want {
+			t.Errorf("Matches(%v) = %v, want %v", c.v, got, c.want)
+		}
+	}
+}
+
+func TestCobaltVersionAlias(t *testing.T) {
+	v0, err := cobaltVersionAlias(0)
+	if err != nil || v0 != (SemVer{Major: 0}) {
+		t.Errorf("Expected legacy '0' to alias to 0.0.0, got %v, %v", v0, err)
+	}
+
+	v1, err := cobaltVersionAlias(1)
+	if err != nil || v1 != (SemVer{Major: 1, Minor: 0, Patch: 0}) {
+		t.Errorf("Expected legacy '1' to alias to 1.0.0, got %v, %v", v1, err)
+	}
+
+	if _, err := cobaltVersionAlias(2); err == nil {
+		t.Error("Expected an error aliasing an unrecognized legacy cobalt_version.")
+	}
+}
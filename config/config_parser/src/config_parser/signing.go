@@ -0,0 +1,57 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains helpers for signing and verifying Cobalt config bundles
+// with ed25519, so a compromised config-hosting repo cannot silently redirect
+// Cobalt telemetry aggregation: consumers that pass -verify_key refuse to
+// proceed unless the bundle they fetched carries a signature from a key they
+// trust.
+
+package config_parser
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+)
+
+// LoadEd25519PrivateKey reads a raw 64 byte ed25519 private key from path, as
+// produced by `ed25519.GenerateKey` and written out with ioutil.WriteFile.
+func LoadEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading private key '%v': %v", path, err)
+	}
+	if len(b) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("Private key '%v' is %v bytes, expected %v.", path, len(b), ed25519.PrivateKeySize)
+	}
+	return ed25519.PrivateKey(b), nil
+}
+
+// LoadEd25519PublicKey reads a raw 32 byte ed25519 public key from path.
+func LoadEd25519PublicKey(path string) (ed25519.PublicKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading public key '%v': %v", path, err)
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("Public key '%v' is %v bytes, expected %v.", path, len(b), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// SignSHA256 signs the sha256 digest of data with key and returns the
+// signature.
+func SignSHA256(key ed25519.PrivateKey, data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return ed25519.Sign(key, sum[:])
+}
+
+// VerifySHA256 reports whether sig is a valid ed25519 signature by key over
+// the sha256 digest of data.
+func VerifySHA256(key ed25519.PublicKey, data, sig []byte) bool {
+	sum := sha256.Sum256(data)
+	return ed25519.Verify(key, sum[:], sig)
+}
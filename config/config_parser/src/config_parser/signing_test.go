@@ -0,0 +1,68 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSignAndVerifySHA256(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+
+	data := []byte("some config bytes")
+	sig := SignSHA256(priv, data)
+
+	if !VerifySHA256(pub, data, sig) {
+		t.Errorf("Valid signature failed to verify.")
+	}
+
+	if VerifySHA256(pub, []byte("tampered"), sig) {
+		t.Errorf("Signature verified against the wrong data.")
+	}
+}
+
+func TestLoadEd25519Keys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobalt_config_keys_test")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+
+	privPath := path.Join(dir, "key.priv")
+	pubPath := path.Join(dir, "key.pub")
+	if err := ioutil.WriteFile(privPath, priv, 0600); err != nil {
+		t.Fatalf("Error writing private key: %v", err)
+	}
+	if err := ioutil.WriteFile(pubPath, pub, 0644); err != nil {
+		t.Fatalf("Error writing public key: %v", err)
+	}
+
+	loadedPriv, err := LoadEd25519PrivateKey(privPath)
+	if err != nil {
+		t.Fatalf("Error loading private key: %v", err)
+	}
+	loadedPub, err := LoadEd25519PublicKey(pubPath)
+	if err != nil {
+		t.Fatalf("Error loading public key: %v", err)
+	}
+
+	data := []byte("some config bytes")
+	if !VerifySHA256(loadedPub, data, SignSHA256(loadedPriv, data)) {
+		t.Errorf("Round tripped keys failed to sign/verify correctly.")
+	}
+}
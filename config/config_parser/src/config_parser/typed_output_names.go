@@ -0,0 +1,58 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// Naming helpers shared by the typed-constant output formatters (Rust,
+// TypeScript), which both need to turn a Cobalt metric_name/report_name
+// (snake_case, matching validNameRegexp) into an identifier that is
+// idiomatic in the target language.
+
+package config_parser
+
+import "strings"
+
+// rustConstName turns a snake_case Cobalt name into a SCREAMING_SNAKE_CASE
+// Rust constant name.
+func rustConstName(name string) string {
+	return strings.ToUpper(name)
+}
+
+// rustTypeName turns a snake_case Cobalt name into an UpperCamelCase Rust
+// type/variant name.
+func rustTypeName(name string) string {
+	return camelCase(name, true)
+}
+
+// tsConstName turns a snake_case Cobalt name into a SCREAMING_SNAKE_CASE
+// TypeScript constant name.
+func tsConstName(name string) string {
+	return strings.ToUpper(name)
+}
+
+// tsTypeName turns a snake_case Cobalt name into an UpperCamelCase TypeScript
+// type/enum name.
+func tsTypeName(name string) string {
+	return camelCase(name, true)
+}
+
+// camelCase splits name on non-alphanumeric characters and joins the parts in
+// CamelCase, capitalizing the first part iff upperFirst is set.
+func camelCase(name string, upperFirst bool) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+
+	var b strings.Builder
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		if i == 0 && !upperFirst {
+			b.WriteString(p)
+			continue
+		}
+		b.WriteString(strings.ToUpper(p[:1]))
+		b.WriteString(p[1:])
+	}
+	return b.String()
+}
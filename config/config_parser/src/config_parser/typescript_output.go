@@ -0,0 +1,70 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains a TypeScriptOutputFactory that behaves like
+// CppOutputFactory and DartOutputFactory, but emits a TypeScript file of
+// `export const enum` blocks instead of a serialized proto, so TypeScript
+// clients can refer to metric/report ids and event codes by name without
+// parsing the binary config at runtime.
+
+package config_parser
+
+import (
+	"bytes"
+	"config"
+	"fmt"
+	"sort"
+)
+
+// TypeScriptOutputFactory returns an OutputFormatter that writes a .ts file
+// declaring an `export const enum` of metric ids, one of report ids per
+// metric, and one of event codes for every metric that declares any.
+// enumPrefix is prepended to every generated enum name.
+func TypeScriptOutputFactory(enumPrefix string) OutputFormatter {
+	return func(c *config.CobaltConfig) ([]byte, error) {
+		var b bytes.Buffer
+
+		fmt.Fprintf(&b, "// Generated by the Cobalt config_parser. DO NOT EDIT.\n\n")
+
+		fmt.Fprintf(&b, "export const enum %sMetricIds {\n", enumPrefix)
+		for _, m := range c.MetricDefinitions {
+			fmt.Fprintf(&b, "  %s = %d,\n", tsConstName(m.MetricName), m.Id)
+		}
+		fmt.Fprintf(&b, "}\n\n")
+
+		for _, m := range c.MetricDefinitions {
+			if len(m.Reports) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "export const enum %s%sReportIds {\n", enumPrefix, tsTypeName(m.MetricName))
+			for _, r := range m.Reports {
+				fmt.Fprintf(&b, "  %s = %d,\n", tsConstName(r.ReportName), r.Id)
+			}
+			fmt.Fprintf(&b, "}\n\n")
+		}
+
+		for _, m := range c.MetricDefinitions {
+			if len(m.EventTypes) == 0 {
+				continue
+			}
+			writeTypeScriptEventCodeEnum(&b, enumPrefix, m)
+		}
+
+		return b.Bytes(), nil
+	}
+}
+
+func writeTypeScriptEventCodeEnum(b *bytes.Buffer, enumPrefix string, m *config.MetricDefinition) {
+	codes := make([]uint32, 0, len(m.EventTypes))
+	for code := range m.EventTypes {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	fmt.Fprintf(b, "export const enum %s%sEventCodes {\n", enumPrefix, tsTypeName(m.MetricName))
+	for _, code := range codes {
+		fmt.Fprintf(b, "  %s = %d,\n", tsConstName(m.EventTypes[code]), code)
+	}
+	fmt.Fprintf(b, "}\n\n")
+}
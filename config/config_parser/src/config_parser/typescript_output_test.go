@@ -0,0 +1,45 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_parser
+
+import (
+	"config"
+	"strings"
+	"testing"
+)
+
+func TestTypeScriptOutputFactory(t *testing.T) {
+	c := config.CobaltConfig{
+		MetricDefinitions: []*config.MetricDefinition{
+			{
+				MetricName: "the_metric_name",
+				Id:         42,
+				EventTypes: map[uint32]string{0: "an_event"},
+				Reports: []*config.ReportDefinition{
+					{ReportName: "the_report", Id: 7},
+				},
+			},
+		},
+	}
+
+	b, err := TypeScriptOutputFactory("Cobalt")(&c)
+	if err != nil {
+		t.Fatalf("Error generating TypeScript output: %v", err)
+	}
+	out := string(b)
+
+	for _, want := range []string{
+		"export const enum CobaltMetricIds {",
+		"THE_METRIC_NAME = 42,",
+		"export const enum CobaltTheMetricNameReportIds {",
+		"THE_REPORT = 7,",
+		"export const enum CobaltTheMetricNameEventCodes {",
+		"AN_EVENT = 0,",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected generated TypeScript to contain %q, got:\n%v", want, out)
+		}
+	}
+}
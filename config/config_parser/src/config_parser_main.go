@@ -10,6 +10,7 @@ package main
 import (
 	"config_parser"
 	"config_validator"
+	"crypto/ed25519"
 	"flag"
 	"fmt"
 	"io"
@@ -22,24 +23,33 @@ import (
 )
 
 var (
-	repoUrl        = flag.String("repo_url", "", "URL of the repository containing the config. Exactly one of 'repo_url', 'config_file' or 'config_dir' must be specified.")
-	configDir      = flag.String("config_dir", "", "Directory containing the config. Exactly one of 'repo_url', 'config_file' or 'config_dir' must be specified.")
-	configFile     = flag.String("config_file", "", "File containing the config for a single project. Exactly one of 'repo_url', 'config_file' or 'config_dir' must be specified.")
-	outFile        = flag.String("output_file", "", "File to which the serialized config should be written. Defaults to stdout. When multiple output formats are specified, it will append the format to the filename")
-	outFilename    = flag.String("out_filename", "", "The base name to use for writing files. Should not be used with output_file.")
-	outDir         = flag.String("out_dir", "", "The directory into which files should be written.")
-	dartOutDir     = flag.String("dart_out_dir", "", "The directory to write dart files to (if different from out_dir)")
-	addFileSuffix  = flag.Bool("add_file_suffix", false, "Append the out_format to the out_file, even if there is only one out_format specified")
-	checkOnly      = flag.Bool("check_only", false, "Only check that the configuration is valid.")
-	skipValidation = flag.Bool("skip_validation", false, "Skip validating the config, write it no matter what.")
-	gitTimeoutSec  = flag.Int64("git_timeout", 60, "How many seconds should I wait on git commands?")
-	customerId     = flag.Int64("customer_id", -1, "Customer Id for the config to be read. Must be set if and only if 'config_file' is set.")
-	projectId      = flag.Int64("project_id", -1, "Project Id for the config to be read. Must be set if and only if 'config_file' is set.")
-	projectName    = flag.String("project_name", "", "Project name for the config to be read. Must be set if and only if 'config_dir' is set.")
-	outFormat      = flag.String("out_format", "bin", "Specifies the output formats (separated by ' '). Supports 'bin' (serialized proto), 'b64' (serialized proto to base 64), 'cpp' (a C++ file containing a variable with a base64-encoded serialized proto.) and 'dart' (a Dart file...)")
-	varName        = flag.String("var_name", "config", "When using the 'cpp' or 'dart' output format, this will specify the variable name to be used in the output.")
-	namespace      = flag.String("namespace", "", "When using the 'cpp' output format, this will specify the comma-separated namespace within which the config variable must be places.")
-	depFile        = flag.String("dep_file", "", "Generate a depfile (see gn documentation) that lists all the project configuration files. Requires -output_file and -config_dir.")
+	repoUrl         = flag.String("repo_url", "", "URL of the repository containing the config. Exactly one of 'repo_url', 'config_file' or 'config_dir' must be specified.")
+	configDir       = flag.String("config_dir", "", "Directory containing the config. Exactly one of 'repo_url', 'config_file' or 'config_dir' must be specified.")
+	configFile      = flag.String("config_file", "", "File containing the config for a single project. Exactly one of 'repo_url', 'config_file' or 'config_dir' must be specified.")
+	outFile         = flag.String("output_file", "", "File to which the serialized config should be written. Defaults to stdout. When multiple output formats are specified, it will append the format to the filename")
+	outFilename     = flag.String("out_filename", "", "The base name to use for writing files. Should not be used with output_file.")
+	outDir          = flag.String("out_dir", "", "The directory into which files should be written.")
+	dartOutDir      = flag.String("dart_out_dir", "", "The directory to write dart files to (if different from out_dir)")
+	addFileSuffix   = flag.Bool("add_file_suffix", false, "Append the out_format to the out_file, even if there is only one out_format specified")
+	checkOnly       = flag.Bool("check_only", false, "Only check that the configuration is valid.")
+	skipValidation  = flag.Bool("skip_validation", false, "Skip validating the config, write it no matter what.")
+	gitTimeoutSec   = flag.Int64("git_timeout", 60, "How many seconds should I wait on git commands?")
+	lockFile        = flag.String("lock_file", "", "Path to a cobalt_config.lock file recording the resolved git commit and per-project hashes of the config read from 'repo_url'. If set without --frozen, the lockfile is (re)written after a successful read. Requires 'repo_url'.")
+	frozen          = flag.Bool("frozen", false, "Require that 'repo_url' resolves to exactly the commit and contents recorded in 'lock_file', and fail otherwise. Requires 'lock_file'.")
+	customerId      = flag.Int64("customer_id", -1, "Customer Id for the config to be read. Must be set if and only if 'config_file' is set.")
+	projectId       = flag.Int64("project_id", -1, "Project Id for the config to be read. Must be set if and only if 'config_file' is set.")
+	projectName     = flag.String("project_name", "", "Project name for the config to be read. Must be set if and only if 'config_dir' is set.")
+	outFormat       = flag.String("out_format", "bin", "Specifies the output formats (separated by ' '). Supports 'bin' (serialized proto), 'b64' (serialized proto to base 64), 'cpp' (a C++ file containing a variable with a base64-encoded serialized proto.), 'dart' (a Dart file...), 'rust' (a Rust module of typed constants), 'ts' (a TypeScript file of const enums) and 'jsonschema' (a JSON Schema document describing the project config YAML).")
+	varName         = flag.String("var_name", "config", "When using the 'cpp' or 'dart' output format, this will specify the variable name to be used in the output.")
+	namespace       = flag.String("namespace", "", "When using the 'cpp' output format, this will specify the comma-separated namespace within which the config variable must be places.")
+	depFile         = flag.String("dep_file", "", "Generate a depfile (see gn documentation) that lists the project configuration files: only the named project's files when -project_name is set, otherwise every file under -config_dir. Requires -config_dir and one of -output_file or -out_filename.")
+	depFormat       = flag.String("dep_format", "gn", "Syntax to use for -dep_file. Supports 'gn', 'ninja' and 'make'.")
+	diffAgainst     = flag.String("diff_against", "", "Path to a previous revision of 'config_file' to diff the current config against. Requires 'config_file'. Exits non-zero on breaking changes unless -allow_breaking is set.")
+	allowBreaking   = flag.Bool("allow_breaking", false, "Allow -diff_against to find breaking changes without failing.")
+	signKey         = flag.String("sign_key", "", "Path to a raw ed25519 private key. If set, a detached signature over the sha256 of the 'bin' output is written to '<output>.sig'.")
+	verifyKey       = flag.String("verify_key", "", "Path to a raw ed25519 public key. If set with 'repo_url' pointing at an https tarball, refuse to proceed unless the fetched tarball carries a valid signature from this key.")
+	reportFormat    = flag.String("validation_report", "", "If set to 'text' or 'json', validation issues are accumulated into a config_validator.ValidationReport and rendered in the given format, and the tool only exits non-zero when the report contains an Error (not a Warning or Info). If unset, validation stops at (and exits non-zero on) the first error, as before.")
+	reservedIdsFile = flag.String("reserved_ids_file", "", "Path to a directory holding, per customer/project, a yaml file recording every metric and report id that project has ever used. If set, validation rejects a new metric/report whose hashed id collides with one reserved for a different name within the same project, and each project's file is rewritten with any newly-used ids after a successful validation.")
 )
 
 func generateFilename(format string) string {
@@ -63,16 +73,21 @@ func generateFilename(format string) string {
 }
 
 // Write a depfile listing the files in 'files' at the location specified by
-// outFile.
-func writeDepFile(formats, files []string, depFile string) error {
+// depFile, in the syntax selected by depFormat.
+func writeDepFile(formats, files []string, depFile string, depFormat config_parser.DepFormat) error {
 	w, err := os.Create(depFile)
 	if err != nil {
 		return err
 	}
 	defer w.Close()
 
+	escaped := make([]string, len(files))
+	for i, f := range files {
+		escaped[i] = config_parser.EscapeDepFilePath(f, depFormat)
+	}
+
 	for _, format := range formats {
-		_, err = io.WriteString(w, fmt.Sprintf("%s: %s\n", generateFilename(format), strings.Join(files, " ")))
+		_, err = io.WriteString(w, fmt.Sprintf("%s: %s\n", generateFilename(format), strings.Join(escaped, " ")))
 	}
 	return err
 }
@@ -104,6 +119,26 @@ func main() {
 		glog.Exit("-dep_file requires -config_dir")
 	}
 
+	if *depFile != "" && *projectName != "" && *customerId < 0 {
+		glog.Exit("-dep_file with -project_name also requires -customer_id, since project names are only unique within a customer.")
+	}
+
+	if *diffAgainst != "" && *configFile == "" {
+		glog.Exit("-diff_against requires -config_file")
+	}
+
+	if *allowBreaking && *diffAgainst == "" {
+		glog.Exit("-allow_breaking requires -diff_against")
+	}
+
+	if (*lockFile != "" || *frozen) && *repoUrl == "" {
+		glog.Exit("-lock_file and -frozen require -repo_url")
+	}
+
+	if *frozen && *lockFile == "" {
+		glog.Exit("-frozen requires -lock_file")
+	}
+
 	if *depFile != "" && (*outFile == "" && *outFilename == "") {
 		glog.Exit("-dep_file requires -output_file or -out_filename")
 	}
@@ -132,12 +167,40 @@ func main() {
 	outFormats := strings.FieldsFunc(*outFormat, func(c rune) bool { return c == ' ' })
 
 	if *depFile != "" {
-		files, err := config_parser.GetConfigFilesListFromConfigDir(configLocation)
+		format, err := config_parser.ParseDepFormat(*depFormat)
 		if err != nil {
 			glog.Exit(err)
 		}
 
-		if err := writeDepFile(outFormats, files, *depFile); err != nil {
+		var files []string
+		if *projectName != "" {
+			// A single project was requested: only depend on that project's
+			// own files, so that unrelated projects changing in config_dir
+			// doesn't force a rebuild of this target. Project names are only
+			// required to be unique within a customer (populateProjectList),
+			// so resolve the exact customer this project belongs to via
+			// customer_id before keying into filesByProject, instead of
+			// matching on project name alone and risking two customers'
+			// file lists getting concatenated together.
+			pc, err := config_parser.ReadProjectConfigFromDirByName(configLocation, uint32(*customerId), *projectName)
+			if err != nil {
+				glog.Exit(err)
+			}
+
+			filesByProject, err := config_parser.GetConfigFilesListByProject(configLocation)
+			if err != nil {
+				glog.Exit(err)
+			}
+
+			files = filesByProject[pc.CustomerName+"/"+pc.ProjectName]
+		} else {
+			files, err = config_parser.GetConfigFilesListFromConfigDir(configLocation)
+			if err != nil {
+				glog.Exit(err)
+			}
+		}
+
+		if err := writeDepFile(outFormats, files, *depFile, format); err != nil {
 			glog.Exit(err)
 		}
 	}
@@ -148,7 +211,32 @@ func main() {
 	var err error
 	if *repoUrl != "" {
 		gitTimeout := time.Duration(*gitTimeoutSec) * time.Second
-		configs, err = config_parser.ReadConfigFromRepo(*repoUrl, gitTimeout)
+
+		var pubKey ed25519.PublicKey
+		if *verifyKey != "" {
+			if pubKey, err = config_parser.LoadEd25519PublicKey(*verifyKey); err != nil {
+				glog.Exit(err)
+			}
+		}
+
+		var source config_parser.ConfigSource
+		if source, err = config_parser.ResolveConfigSource(*repoUrl, gitTimeout, pubKey); err == nil {
+			configs, err = source.Fetch()
+		}
+
+		if err == nil && (*lockFile != "" || *frozen) {
+			var headSha string
+			if headSha, err = config_parser.ResolveRepoHeadSha(*repoUrl, gitTimeout); err == nil {
+				if *frozen {
+					var lock config_parser.ConfigLock
+					if lock, err = config_parser.ReadLockFile(*lockFile); err == nil {
+						err = config_parser.CheckFrozen(lock, headSha, configs)
+					}
+				} else {
+					err = config_parser.WriteLockFile(*lockFile, config_parser.NewConfigLock(headSha, configs))
+				}
+			}
+		}
 	} else if *configFile != "" {
 		pc, err = config_parser.ReadConfigFromYaml(*configFile, uint32(*customerId), uint32(*projectId))
 		configs = append(configs, pc)
@@ -167,13 +255,71 @@ func main() {
 	}
 
 	if !*skipValidation {
-		for _, c := range configs {
-			if err = config_validator.ValidateProjectConfig(&c); err != nil {
-				glog.Exit(err)
+		if *reservedIdsFile != "" {
+			// Metric/report id uniqueness is only ever checked within a
+			// single project, so each project gets its own registry file
+			// instead of sharing one across every project in configs. Two
+			// unrelated projects whose metric/report names happen to hash to
+			// the same 32-bit id must not conflict with each other.
+			for _, c := range configs {
+				registryPath := config_parser.ReservedIdsPathForProject(*reservedIdsFile, c.CustomerName, c.ProjectName)
+				registry, err := config_parser.ReadReservedIdsRegistry(registryPath)
+				if err != nil {
+					glog.Exit(err)
+				}
+
+				if err = config_validator.ValidateProjectConfigWithReservedIds(&c, &registry); err != nil {
+					glog.Exit(err)
+				}
+
+				if err := config_parser.WriteReservedIdsRegistry(registryPath, registry); err != nil {
+					glog.Exit(err)
+				}
+			}
+		} else if *reportFormat == "" {
+			for _, c := range configs {
+				if err = config_validator.ValidateProjectConfig(&c); err != nil {
+					glog.Exit(err)
+				}
+			}
+		} else {
+			report := config_validator.ValidateAllProjects(configs)
+
+			switch *reportFormat {
+			case "text":
+				fmt.Print(report.RenderText())
+			case "json":
+				b, err := report.RenderJSON()
+				if err != nil {
+					glog.Exit(err)
+				}
+				fmt.Println(string(b))
+			default:
+				glog.Exitf("'%v' is an invalid validation_report format. 'text' and 'json' are the only valid values.", *reportFormat)
+			}
+
+			if report.HasErrors() {
+				glog.Exit("Validation failed. See the issues of severity Error above.")
 			}
 		}
 	}
 
+	if *diffAgainst != "" {
+		oldConfig, err := config_parser.ReadConfigFromYaml(*diffAgainst, uint32(*customerId), uint32(*projectId))
+		if err != nil {
+			glog.Exit(err)
+		}
+
+		changes := config_validator.DiffProjectConfigs(&oldConfig, &pc)
+		for _, change := range changes {
+			fmt.Printf("[%s] %s\n", change.Severity, change.Description)
+		}
+
+		if config_validator.HasBreakingChanges(changes) && !*allowBreaking {
+			glog.Exit("Breaking changes found. Pass -allow_breaking to proceed anyway.")
+		}
+	}
+
 	c := config_parser.MergeConfigs(configs)
 
 	for _, format := range outFormats {
@@ -194,8 +340,14 @@ func main() {
 				glog.Exitf("Dart output can only be used with a single project config.")
 			}
 			outputFormatter = config_parser.DartOutputFactory(*varName)
+		case "rust":
+			outputFormatter = config_parser.RustOutputFactory(*varName)
+		case "ts":
+			outputFormatter = config_parser.TypeScriptOutputFactory(*varName)
+		case "jsonschema":
+			outputFormatter = config_parser.JSONSchemaOutput
 		default:
-			glog.Exitf("'%v' is an invalid out_format parameter. 'bin', 'b64', 'cpp' and 'dart' are the only valid values for out_format.", *outFormat)
+			glog.Exitf("'%v' is an invalid out_format parameter. 'bin', 'b64', 'cpp', 'dart', 'rust', 'ts' and 'jsonschema' are the only valid values for out_format.", *outFormat)
 		}
 
 		// Then, we serialize the configuration.
@@ -256,6 +408,18 @@ func main() {
 				}
 			}
 		}
+
+		if format == "bin" && *signKey != "" && fname != "" {
+			privKey, err := config_parser.LoadEd25519PrivateKey(*signKey)
+			if err != nil {
+				glog.Exit(err)
+			}
+
+			sig := config_parser.SignSHA256(privKey, configBytes)
+			if err := ioutil.WriteFile(fname+".sig", sig, 0644); err != nil {
+				glog.Exit(err)
+			}
+		}
 	}
 
 	os.Exit(0)
@@ -0,0 +1,98 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains config_validator checks that only make sense looking
+// at every MetricDefinition in a project together, rather than validating
+// one metric at a time the way metric_definitions.go/report_definitions.go
+// do: report id collisions across metrics, event_types labels that disagree
+// between metrics for the same index, and SystemProfileFields a report
+// needs but the metric carrying it doesn't supply (the v1 analogue of
+// validateSystemProfileFields, which only covers the v0
+// MetricConfig/ReportConfig world).
+
+package config_validator
+
+import (
+	"config"
+	"config_parser"
+	"fmt"
+)
+
+// checkCrossMetricConsistency appends Issues for invariants that span every
+// MetricDefinition in c. It is only meaningful for Cobalt 1.0 projects.
+func checkCrossMetricConsistency(c *config_parser.ProjectConfig, report *ValidationReport) {
+	if c.CobaltVersion != config_parser.CobaltVersion1 {
+		return
+	}
+
+	checkUniqueReportIds(c, report)
+	checkEventTypeLabelConsistency(c, report)
+	checkSystemProfileFieldsV1(c, report)
+}
+
+// checkUniqueReportIds flags two differently named reports anywhere in the
+// project (on the same metric or on different ones) that hash to the same
+// report id, the same way validateConfiguredMetricDefinitions does for metric
+// ids within a project's metric list.
+func checkUniqueReportIds(c *config_parser.ProjectConfig, report *ValidationReport) {
+	reportNameById := map[uint32]string{}
+	for _, m := range c.ProjectConfig.MetricDefinitions {
+		for _, r := range m.Reports {
+			if other, ok := reportNameById[r.Id]; ok && other != r.ReportName {
+				report.addMetricReportError(c, m.MetricName, r.ReportName, ECodeDuplicateReportId,
+					fmt.Sprintf("Reports '%s' and '%s' hash to the same report id. One must be renamed.", other, r.ReportName))
+				continue
+			}
+			reportNameById[r.Id] = r.ReportName
+		}
+	}
+}
+
+// checkEventTypeLabelConsistency warns when two metrics label the same
+// event_types index differently, since reports that aggregate by that index
+// across metrics would otherwise show inconsistent labels for the same
+// underlying event.
+func checkEventTypeLabelConsistency(c *config_parser.ProjectConfig, report *ValidationReport) {
+	labelByIndex := map[uint32]string{}
+	ownerByIndex := map[uint32]string{}
+	for _, m := range c.ProjectConfig.MetricDefinitions {
+		for index, label := range m.EventTypes {
+			existing, ok := labelByIndex[index]
+			if !ok {
+				labelByIndex[index] = label
+				ownerByIndex[index] = m.MetricName
+				continue
+			}
+			if existing != label {
+				report.addMetricReportWarning(c, m.MetricName, "", WCodeInconsistentEventTypeLabel,
+					fmt.Sprintf("event_types index %d is labeled '%s' on metric '%s' but '%s' on metric '%s'.", index, existing, ownerByIndex[index], label, m.MetricName))
+			}
+		}
+	}
+}
+
+// checkSystemProfileFieldsV1 is the v1 (MetricDefinition/ReportDefinition)
+// analogue of validateSystemProfileFields, which only understands the v0
+// MetricConfig/ReportConfig messages.
+func checkSystemProfileFieldsV1(c *config_parser.ProjectConfig, report *ValidationReport) {
+	for _, m := range c.ProjectConfig.MetricDefinitions {
+		for _, r := range m.Reports {
+			for _, field := range r.SystemProfileField {
+				if !containsMetricDefinitionSystemProfileField(m, field) {
+					report.addMetricReportError(c, m.MetricName, r.ReportName, ECodeMissingSystemProfileField,
+						fmt.Sprintf("Report '%s' uses system_profile_field %v, but metric '%s' does not supply it.", r.ReportName, field, m.MetricName))
+				}
+			}
+		}
+	}
+}
+
+func containsMetricDefinitionSystemProfileField(m *config.MetricDefinition, e config.SystemProfileField) bool {
+	for _, a := range m.SystemProfileField {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
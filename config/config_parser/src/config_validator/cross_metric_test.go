@@ -0,0 +1,92 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_validator
+
+import (
+	"config"
+	"config_parser"
+	"testing"
+)
+
+func TestCheckUniqueReportIdsCollision(t *testing.T) {
+	m1 := makeValidMetricWithName("metric_one")
+	m1.Reports = []*config.ReportDefinition{{ReportName: "the_report", ReportType: config.ReportDefinition_SIMPLE_OCCURRENCE_COUNT, Id: 1}}
+	m2 := makeValidMetricWithName("metric_two")
+	m2.Reports = []*config.ReportDefinition{{ReportName: "a_different_report", ReportType: config.ReportDefinition_SIMPLE_OCCURRENCE_COUNT, Id: 1}}
+
+	c := &config_parser.ProjectConfig{CobaltVersion: config_parser.CobaltVersion1}
+	c.ProjectConfig.MetricDefinitions = []*config.MetricDefinition{&m1, &m2}
+
+	report := &ValidationReport{}
+	checkCrossMetricConsistency(c, report)
+
+	found := false
+	for _, i := range report.Issues {
+		if i.Code == ECodeDuplicateReportId {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a %s error for reports hashing to the same id, got %v", ECodeDuplicateReportId, report.Issues)
+	}
+}
+
+func TestCheckEventTypeLabelConsistency(t *testing.T) {
+	m1 := makeValidMetricWithName("metric_one")
+	m1.EventTypes = map[uint32]string{1: "clicked"}
+	m2 := makeValidMetricWithName("metric_two")
+	m2.EventTypes = map[uint32]string{1: "tapped"}
+
+	c := &config_parser.ProjectConfig{CobaltVersion: config_parser.CobaltVersion1}
+	c.ProjectConfig.MetricDefinitions = []*config.MetricDefinition{&m1, &m2}
+
+	report := &ValidationReport{}
+	checkCrossMetricConsistency(c, report)
+
+	found := false
+	for _, i := range report.Issues {
+		if i.Code == WCodeInconsistentEventTypeLabel {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a %s warning for mismatched event_types labels, got %v", WCodeInconsistentEventTypeLabel, report.Issues)
+	}
+}
+
+func TestCheckSystemProfileFieldsV1Missing(t *testing.T) {
+	m := makeValidMetric()
+	m.Reports = []*config.ReportDefinition{{
+		ReportName:         "the_report",
+		ReportType:         config.ReportDefinition_SIMPLE_OCCURRENCE_COUNT,
+		Id:                 1,
+		SystemProfileField: []config.SystemProfileField{config.SystemProfileField_OS},
+	}}
+
+	c := &config_parser.ProjectConfig{CobaltVersion: config_parser.CobaltVersion1}
+	c.ProjectConfig.MetricDefinitions = []*config.MetricDefinition{&m}
+
+	report := &ValidationReport{}
+	checkCrossMetricConsistency(c, report)
+
+	found := false
+	for _, i := range report.Issues {
+		if i.Code == ECodeMissingSystemProfileField {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a %s error for a report requiring a SystemProfileField its metric doesn't supply, got %v", ECodeMissingSystemProfileField, report.Issues)
+	}
+
+	m.SystemProfileField = []config.SystemProfileField{config.SystemProfileField_OS}
+	report = &ValidationReport{}
+	checkCrossMetricConsistency(c, report)
+	for _, i := range report.Issues {
+		if i.Code == ECodeMissingSystemProfileField {
+			t.Errorf("Unexpected %s error once the metric supplies the field: %v", ECodeMissingSystemProfileField, report.Issues)
+		}
+	}
+}
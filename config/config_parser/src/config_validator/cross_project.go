@@ -0,0 +1,55 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains checks that span an entire customer list rather than a
+// single project: duplicate (customer_id, project_id) pairs and duplicate
+// project names within a customer. parseCustomerList/populateProjectList
+// already reject these within the single yaml document they parse, but
+// config_dir and multi-source config_parser.ConfigSource setups can combine
+// documents from several places, so ValidateAllProjects re-checks the
+// invariant across the combined list.
+
+package config_validator
+
+import (
+	"config_parser"
+	"fmt"
+)
+
+type customerProjectId struct {
+	customerId uint32
+	projectId  uint32
+}
+
+// ValidateAllProjects runs ValidateAll on every entry in configs, and
+// additionally flags (customer_id, project_id) pairs or (customer_id,
+// project_name) pairs that are used by more than one project in the list.
+func ValidateAllProjects(configs []config_parser.ProjectConfig) *ValidationReport {
+	report := &ValidationReport{}
+
+	projectNameById := map[customerProjectId]string{}
+	seenNames := map[uint32]map[string]bool{}
+
+	for _, c := range configs {
+		report.Issues = append(report.Issues, ValidateAll(&c).Issues...)
+
+		id := customerProjectId{c.CustomerId, c.ProjectId}
+		if other, ok := projectNameById[id]; ok && other != c.ProjectName {
+			report.addError(&c, ECodeDuplicateProjectId, fmt.Sprintf("(customer_id, project_id) pair (%d, %d) is used by both '%s' and '%s'.", c.CustomerId, c.ProjectId, other, c.ProjectName))
+		}
+		projectNameById[id] = c.ProjectName
+
+		names, ok := seenNames[c.CustomerId]
+		if !ok {
+			names = map[string]bool{}
+			seenNames[c.CustomerId] = names
+		}
+		if names[c.ProjectName] {
+			report.addError(&c, ECodeDuplicateProjectName, fmt.Sprintf("Project name '%s' is used more than once for customer %d.", c.ProjectName, c.CustomerId))
+		}
+		names[c.ProjectName] = true
+	}
+
+	return report
+}
@@ -0,0 +1,62 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_validator
+
+import (
+	"config_parser"
+	"testing"
+)
+
+func TestValidateAllProjectsDuplicateProjectId(t *testing.T) {
+	configs := []config_parser.ProjectConfig{
+		{CustomerId: 1, ProjectId: 1, ProjectName: "a_project", CobaltVersion: config_parser.CobaltVersion1},
+		{CustomerId: 1, ProjectId: 1, ProjectName: "a_different_project", CobaltVersion: config_parser.CobaltVersion1},
+	}
+
+	report := ValidateAllProjects(configs)
+
+	found := false
+	for _, i := range report.Issues {
+		if i.Code == ECodeDuplicateProjectId {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a %s error for a repeated (customer_id, project_id) pair, got %v", ECodeDuplicateProjectId, report.Issues)
+	}
+}
+
+func TestValidateAllProjectsDuplicateProjectName(t *testing.T) {
+	configs := []config_parser.ProjectConfig{
+		{CustomerId: 1, ProjectId: 1, ProjectName: "a_project", CobaltVersion: config_parser.CobaltVersion1},
+		{CustomerId: 1, ProjectId: 2, ProjectName: "a_project", CobaltVersion: config_parser.CobaltVersion1},
+	}
+
+	report := ValidateAllProjects(configs)
+
+	found := false
+	for _, i := range report.Issues {
+		if i.Code == ECodeDuplicateProjectName {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a %s error for a repeated project name within a customer, got %v", ECodeDuplicateProjectName, report.Issues)
+	}
+}
+
+func TestValidateAllProjectsNoDuplicates(t *testing.T) {
+	configs := []config_parser.ProjectConfig{
+		{CustomerId: 1, ProjectId: 1, ProjectName: "a_project", CobaltVersion: config_parser.CobaltVersion1},
+		{CustomerId: 2, ProjectId: 1, ProjectName: "a_project", CobaltVersion: config_parser.CobaltVersion1},
+	}
+
+	report := ValidateAllProjects(configs)
+	for _, i := range report.Issues {
+		if i.Code == ECodeDuplicateProjectId || i.Code == ECodeDuplicateProjectName {
+			t.Errorf("Unexpected duplicate issue for distinct customers: %v", report.Issues)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains logic to classify the differences between two
+// revisions of the same project's config as safe or breaking. Cobalt ids are
+// derived from names via config_parser.IdFromName, so a rename that looks
+// harmless in a code review produces silent id churn that invalidates
+// historical data; DiffProjectConfigs exists to catch that class of
+// regression before it ships, the way an API-compat checker does for a
+// library release. A metric may set allow_breaking_change: true to declare
+// that it has already been through that review and ship the change anyway.
+
+package config_validator
+
+import (
+	"config"
+	"config_parser"
+	"fmt"
+	"time"
+)
+
+// ChangeSeverity classifies whether a Change is expected to be compatible
+// with data collected under the previous revision of a config.
+type ChangeSeverity int
+
+const (
+	// Safe changes cannot invalidate or misattribute already-collected data.
+	Safe ChangeSeverity = iota
+	// Breaking changes can invalidate or misattribute already-collected data.
+	Breaking
+)
+
+func (s ChangeSeverity) String() string {
+	if s == Breaking {
+		return "breaking"
+	}
+	return "safe"
+}
+
+// Change describes a single difference found between two revisions of a
+// project's config.
+type Change struct {
+	Severity    ChangeSeverity
+	Description string
+}
+
+// HasBreakingChanges returns true iff any of changes is Breaking.
+func HasBreakingChanges(changes []Change) bool {
+	for _, c := range changes {
+		if c.Severity == Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffProjectConfigs compares the MetricDefinitions of old and new (which
+// are expected to be two revisions of the same project) and returns the list
+// of Changes between them. It is only meaningful for Cobalt 1.0 projects.
+func DiffProjectConfigs(old, new *config_parser.ProjectConfig) (changes []Change) {
+	return diffMetricDefinitionLists(old.ProjectConfig.MetricDefinitions, new.ProjectConfig.MetricDefinitions)
+}
+
+// diffMetricDefinitionLists compares old and new, pairing metrics first by
+// name and then, among the metrics left over, by id (a metric that kept its
+// id across a rename is deliberately treated as safe, since preserving the
+// id is what makes the rename safe in the first place).
+func diffMetricDefinitionLists(old, new []*config.MetricDefinition) (changes []Change) {
+	oldMetrics := metricsByName(old)
+	newMetrics := metricsByName(new)
+
+	removedById := map[uint32]*config.MetricDefinition{}
+	for name, m := range oldMetrics {
+		if _, ok := newMetrics[name]; !ok {
+			removedById[m.Id] = m
+		}
+	}
+
+	for name, m := range newMetrics {
+		oldMetric, ok := oldMetrics[name]
+		if ok {
+			changes = append(changes, diffMetricDefinition(*oldMetric, *m)...)
+			continue
+		}
+
+		if renamedFrom, ok := removedById[m.Id]; ok {
+			changes = append(changes, Change{Safe, fmt.Sprintf("Metric '%s' was renamed to '%s' (id %d was preserved).", renamedFrom.MetricName, m.MetricName, m.Id)})
+			delete(removedById, m.Id)
+			continue
+		}
+
+		changes = append(changes, Change{Safe, fmt.Sprintf("Metric '%s' was added.", name)})
+	}
+
+	for _, m := range removedById {
+		changes = append(changes, breakingUnless(m.AllowBreakingChange, fmt.Sprintf("Metric '%s' was removed.", m.MetricName)))
+	}
+
+	return changes
+}
+
+// breakingUnless returns a Breaking Change unless allowOverride is set, in
+// which case the change is reported as Safe with a note that it was
+// explicitly allowed.
+func breakingUnless(allowOverride bool, description string) Change {
+	if allowOverride {
+		return Change{Safe, fmt.Sprintf("%s (allowed by allow_breaking_change)", description)}
+	}
+	return Change{Breaking, description}
+}
+
+func diffMetricDefinition(old, new config.MetricDefinition) (changes []Change) {
+	allowOverride := new.AllowBreakingChange
+
+	if old.MetricType != new.MetricType {
+		changes = append(changes, breakingUnless(allowOverride, fmt.Sprintf("Metric '%s' changed metric_type from %s to %s.", new.MetricName, old.MetricType, new.MetricType)))
+	}
+
+	if new.MaxEventTypeIndex < old.MaxEventTypeIndex {
+		changes = append(changes, breakingUnless(allowOverride, fmt.Sprintf("Metric '%s' shrunk max_event_type_index from %d to %d.", new.MetricName, old.MaxEventTypeIndex, new.MaxEventTypeIndex)))
+	} else if new.MaxEventTypeIndex > old.MaxEventTypeIndex {
+		changes = append(changes, Change{Safe, fmt.Sprintf("Metric '%s' grew max_event_type_index from %d to %d.", new.MetricName, old.MaxEventTypeIndex, new.MaxEventTypeIndex)})
+	}
+
+	if intBucketsString(old.IntBuckets) != intBucketsString(new.IntBuckets) {
+		changes = append(changes, breakingUnless(allowOverride, fmt.Sprintf("Metric '%s' redefined int_buckets.", new.MetricName)))
+	}
+
+	changes = append(changes, diffMetadata(new.MetricName, old.MetaData, new.MetaData, allowOverride)...)
+	changes = append(changes, diffReportDefinitions(new.MetricName, old.Reports, new.Reports, allowOverride)...)
+
+	return changes
+}
+
+func intBucketsString(b *config.IntegerBuckets) string {
+	if b == nil {
+		return ""
+	}
+	return b.String()
+}
+
+// diffMetadata compares the two metrics' Metadata, flagging an earlier
+// expiration_date or a lower max_release_stage than before as breaking,
+// since both can stop data collection a downstream consumer is relying on.
+func diffMetadata(metricName string, old, new *config.MetricDefinition_Metadata, allowOverride bool) (changes []Change) {
+	if old == nil || new == nil {
+		return nil
+	}
+
+	oldExp, oldErr := time.ParseInLocation(dateFormat, old.ExpirationDate, time.UTC)
+	newExp, newErr := time.ParseInLocation(dateFormat, new.ExpirationDate, time.UTC)
+	if oldErr == nil && newErr == nil && newExp.Before(oldExp) {
+		changes = append(changes, breakingUnless(allowOverride, fmt.Sprintf("Metric '%s' moved expiration_date earlier, from %s to %s.", metricName, old.ExpirationDate, new.ExpirationDate)))
+	}
+
+	if new.MaxReleaseStage < old.MaxReleaseStage {
+		changes = append(changes, breakingUnless(allowOverride, fmt.Sprintf("Metric '%s' downgraded max_release_stage from %s to %s.", metricName, old.MaxReleaseStage, new.MaxReleaseStage)))
+	}
+
+	return changes
+}
+
+func diffReportDefinitions(metricName string, old, new []*config.ReportDefinition, allowOverride bool) (changes []Change) {
+	oldReports := reportsByName(old)
+	newReports := reportsByName(new)
+
+	for name := range oldReports {
+		if _, ok := newReports[name]; !ok {
+			changes = append(changes, breakingUnless(allowOverride, fmt.Sprintf("Report '%s' on metric '%s' was removed (or renamed, which changes its id).", name, metricName)))
+		}
+	}
+
+	for name, r := range newReports {
+		oldReport, ok := oldReports[name]
+		if !ok {
+			changes = append(changes, Change{Safe, fmt.Sprintf("Report '%s' was added to metric '%s'.", name, metricName)})
+			continue
+		}
+		if oldReport.ReportType != r.ReportType {
+			changes = append(changes, breakingUnless(allowOverride, fmt.Sprintf("Report '%s' on metric '%s' changed report_type from %s to %s.", name, metricName, oldReport.ReportType, r.ReportType)))
+		}
+	}
+
+	return changes
+}
+
+func metricsByName(metrics []*config.MetricDefinition) map[string]*config.MetricDefinition {
+	m := map[string]*config.MetricDefinition{}
+	for _, metric := range metrics {
+		m[metric.MetricName] = metric
+	}
+	return m
+}
+
+func reportsByName(reports []*config.ReportDefinition) map[string]*config.ReportDefinition {
+	m := map[string]*config.ReportDefinition{}
+	for _, r := range reports {
+		m[r.ReportName] = r
+	}
+	return m
+}
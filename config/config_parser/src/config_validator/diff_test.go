@@ -0,0 +1,124 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_validator
+
+import (
+	"config"
+	"config_parser"
+	"testing"
+)
+
+func projectWithMetrics(metrics ...*config.MetricDefinition) *config_parser.ProjectConfig {
+	return &config_parser.ProjectConfig{
+		ProjectConfig: config.CobaltConfig{MetricDefinitions: metrics},
+	}
+}
+
+func TestDiffProjectConfigsNoChanges(t *testing.T) {
+	m := makeValidMetric()
+	old := projectWithMetrics(&m)
+	new := projectWithMetrics(&m)
+
+	if changes := DiffProjectConfigs(old, new); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+}
+
+func TestDiffProjectConfigsAddedMetricIsSafe(t *testing.T) {
+	m := makeValidMetric()
+	old := projectWithMetrics()
+	new := projectWithMetrics(&m)
+
+	changes := DiffProjectConfigs(old, new)
+	if len(changes) != 1 || changes[0].Severity != Safe {
+		t.Errorf("Expected a single safe change, got %v", changes)
+	}
+}
+
+func TestDiffProjectConfigsRemovedMetricIsBreaking(t *testing.T) {
+	m := makeValidMetric()
+	old := projectWithMetrics(&m)
+	new := projectWithMetrics()
+
+	changes := DiffProjectConfigs(old, new)
+	if !HasBreakingChanges(changes) {
+		t.Errorf("Expected a breaking change, got %v", changes)
+	}
+}
+
+func TestDiffProjectConfigsMetricTypeChangeIsBreaking(t *testing.T) {
+	oldMetric := makeValidMetric()
+	newMetric := oldMetric
+	newMetric.MetricType = config.MetricDefinition_ELAPSED_TIME
+
+	old := projectWithMetrics(&oldMetric)
+	new := projectWithMetrics(&newMetric)
+
+	changes := DiffProjectConfigs(old, new)
+	if !HasBreakingChanges(changes) {
+		t.Errorf("Expected a breaking change for a metric_type change, got %v", changes)
+	}
+}
+
+func TestDiffProjectConfigsShrunkMaxEventTypeIndexIsBreaking(t *testing.T) {
+	oldMetric := makeValidMetric()
+	oldMetric.MaxEventTypeIndex = 5
+	newMetric := oldMetric
+	newMetric.MaxEventTypeIndex = 2
+
+	old := projectWithMetrics(&oldMetric)
+	new := projectWithMetrics(&newMetric)
+
+	changes := DiffProjectConfigs(old, new)
+	if !HasBreakingChanges(changes) {
+		t.Errorf("Expected a breaking change for a shrunk max_event_type_index, got %v", changes)
+	}
+}
+
+func TestDiffProjectConfigsRenamePreservingIdIsSafe(t *testing.T) {
+	oldMetric := makeValidMetric()
+	newMetric := oldMetric
+	newMetric.MetricName = "a_new_name"
+
+	old := projectWithMetrics(&oldMetric)
+	new := projectWithMetrics(&newMetric)
+
+	changes := DiffProjectConfigs(old, new)
+	if HasBreakingChanges(changes) {
+		t.Errorf("Expected a rename that preserves the id to be safe, got %v", changes)
+	}
+}
+
+func TestDiffProjectConfigsMaxReleaseStageDowngradeIsBreaking(t *testing.T) {
+	oldMetric := makeValidMetric()
+	oldMetric.MetaData.MaxReleaseStage = config.ReleaseStage_GA
+	newMetric := oldMetric
+	newMetadata := *oldMetric.MetaData
+	newMetric.MetaData = &newMetadata
+	newMetric.MetaData.MaxReleaseStage = config.ReleaseStage_DEBUG
+
+	old := projectWithMetrics(&oldMetric)
+	new := projectWithMetrics(&newMetric)
+
+	changes := DiffProjectConfigs(old, new)
+	if !HasBreakingChanges(changes) {
+		t.Errorf("Expected a breaking change for a max_release_stage downgrade, got %v", changes)
+	}
+}
+
+func TestDiffProjectConfigsAllowBreakingChangeOverride(t *testing.T) {
+	oldMetric := makeValidMetric()
+	newMetric := oldMetric
+	newMetric.MetricType = config.MetricDefinition_ELAPSED_TIME
+	newMetric.AllowBreakingChange = true
+
+	old := projectWithMetrics(&oldMetric)
+	new := projectWithMetrics(&newMetric)
+
+	changes := DiffProjectConfigs(old, new)
+	if HasBreakingChanges(changes) {
+		t.Errorf("Expected allow_breaking_change to downgrade the change to safe, got %v", changes)
+	}
+}
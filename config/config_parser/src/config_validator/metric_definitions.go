@@ -23,8 +23,14 @@ const dateFormat = "2006/01/02"
 // as a C variable name.
 var validNameRegexp = regexp.MustCompile("^[a-zA-Z][_a-zA-Z0-9]{1,65}$")
 
-// Validate a list of MetricDefinitions.
-func validateConfiguredMetricDefinitions(metrics []*config.MetricDefinition) (err error) {
+// customMetricTypeVersions is the range of cobalt_version for which the
+// CUSTOM metric type may be used.
+var customMetricTypeVersions = config_parser.MustParseVersionRange(">=1.0.0")
+
+// Validate a list of MetricDefinitions. version is the project's
+// cobalt_version, used to gate version-specific rules such as
+// customMetricTypeVersions.
+func validateConfiguredMetricDefinitions(metrics []*config.MetricDefinition, hasher config_parser.IdHasher, version config_parser.SemVer) (err error) {
 	metricIds := map[uint32]int{}
 	for i, metric := range metrics {
 		if ci, ok := metricIds[metric.Id]; ok {
@@ -32,7 +38,7 @@ func validateConfiguredMetricDefinitions(metrics []*config.MetricDefinition) (er
 		}
 		metricIds[metric.Id] = i
 
-		if err = validateMetricDefinition(*metric); err != nil {
+		if err = validateMetricDefinition(*metric, hasher, version); err != nil {
 			return fmt.Errorf("Error validating metric '%s': %v", metric.MetricName, err)
 		}
 	}
@@ -40,13 +46,38 @@ func validateConfiguredMetricDefinitions(metrics []*config.MetricDefinition) (er
 	return nil
 }
 
-// Validate a single MetricDefinition.
-func validateMetricDefinition(m config.MetricDefinition) (err error) {
+// ECodeInvalidMetric is the code ValidateAll's aggregating path uses for a
+// single metric definition (or one of its reports) failing validation.
+const ECodeInvalidMetric = "E_INVALID_METRIC"
+
+// collectMetricDefinitionIssues validates every metric in metrics the same
+// way validateConfiguredMetricDefinitions does, but appends each failure to
+// report as its own Issue instead of returning at the first one. This is
+// what lets ValidateAll surface every problem in a config in a single run
+// rather than one per round-trip.
+func collectMetricDefinitionIssues(c *config_parser.ProjectConfig, metrics []*config.MetricDefinition, hasher config_parser.IdHasher, report *ValidationReport) {
+	metricIds := map[uint32]int{}
+	for i, metric := range metrics {
+		if ci, ok := metricIds[metric.Id]; ok {
+			report.addMetricReportError(c, metric.MetricName, "", ECodeInvalidMetric, fmt.Sprintf("Metrics named '%s' and '%s' hash to the same metric ids. One must be renamed.", metric.MetricName, metrics[ci].MetricName))
+			continue
+		}
+		metricIds[metric.Id] = i
+
+		if err := validateMetricDefinition(*metric, hasher, c.Version); err != nil {
+			report.addMetricReportError(c, metric.MetricName, "", ECodeInvalidMetric, err.Error())
+		}
+	}
+}
+
+// Validate a single MetricDefinition. version is the project's
+// cobalt_version, used to gate version-specific rules.
+func validateMetricDefinition(m config.MetricDefinition, hasher config_parser.IdHasher, version config_parser.SemVer) (err error) {
 	if !validNameRegexp.MatchString(m.MetricName) {
 		return fmt.Errorf("Invalid metric name. Metric names must match the regular expression '%v'.", validNameRegexp)
 	}
 
-	if m.Id != config_parser.IdFromName(m.MetricName) {
+	if m.Id != hasher.Hash(m.MetricName) {
 		return fmt.Errorf("Metric id specified in config file. Metric ids may not be set by users.")
 	}
 
@@ -78,11 +109,11 @@ func validateMetricDefinition(m config.MetricDefinition) (err error) {
 		return fmt.Errorf("Metric %s has parts set. parts can only be set for metrics for metric type CUSTOM.", m.MetricName)
 	}
 
-	if err := validateMetricDefinitionForType(m); err != nil {
+	if err := validateMetricDefinitionForType(m, version); err != nil {
 		return fmt.Errorf("Metric %s: %v", m.MetricName, err)
 	}
 
-	return validateReportDefinitions(m)
+	return validateReportDefinitions(m, hasher)
 }
 
 // Validate a single instance of Metadata.
@@ -143,7 +174,7 @@ func validateEventTypes(m config.MetricDefinition) error {
 // Validation for specific metric types:
 ///////////////////////////////////////////////////////////////
 
-func validateMetricDefinitionForType(m config.MetricDefinition) error {
+func validateMetricDefinitionForType(m config.MetricDefinition, version config_parser.SemVer) error {
 	switch m.MetricType {
 	case config.MetricDefinition_EVENT_OCCURRED:
 		return validateEventOccurred(m)
@@ -160,7 +191,7 @@ func validateMetricDefinitionForType(m config.MetricDefinition) error {
 	case config.MetricDefinition_STRING_USED:
 		return validateStringUsed(m)
 	case config.MetricDefinition_CUSTOM:
-		return validateCustom(m)
+		return validateCustom(m, version)
 	}
 
 	return fmt.Errorf("Unknown MetricType: %v", m.MetricType)
@@ -178,11 +209,118 @@ func validateIntHistogram(m config.MetricDefinition) error {
 		return fmt.Errorf("No int_buckets specified for metric of type INT_HISTOGRAM.")
 	}
 
-	// TODO(azani): Validate bucket definition.
+	if _, _, err := intBucketsShape(*m.IntBuckets); err != nil {
+		return fmt.Errorf("Invalid int_buckets: %v", err)
+	}
 
 	return validateEventTypes(m)
 }
 
+// maxIntHistogramBuckets caps the number of buckets an INT_HISTOGRAM metric
+// may define, to bound the size of the reports generated from it.
+const maxIntHistogramBuckets = 500
+
+// intBucketsShape validates b and returns the number of buckets it defines
+// and their average width (top boundary minus floor, divided by the bucket
+// count), which privacy.go uses to judge whether local-DP noise dwarfs the
+// signal in the resulting histogram.
+func intBucketsShape(b config.IntegerBuckets) (numBuckets int64, width float64, err error) {
+	set := 0
+	var floor, top int64
+
+	if b.Linear != nil {
+		set++
+		l := b.Linear
+		if l.NumBuckets < 1 {
+			return 0, 0, fmt.Errorf("linear.num_buckets must be at least 1.")
+		}
+		if l.StepSize <= 0 {
+			return 0, 0, fmt.Errorf("linear.step_size must be positive.")
+		}
+		if intMulOverflows(l.StepSize, l.NumBuckets) {
+			return 0, 0, fmt.Errorf("linear overflows int64 computing its top boundary.")
+		}
+		span := l.StepSize * l.NumBuckets
+		if intAddOverflows(l.Floor, span) {
+			return 0, 0, fmt.Errorf("linear overflows int64 computing its top boundary.")
+		}
+		floor, top, numBuckets = l.Floor, l.Floor+span, l.NumBuckets
+	}
+
+	if b.Exponential != nil {
+		set++
+		e := b.Exponential
+		if e.NumBuckets < 1 {
+			return 0, 0, fmt.Errorf("exponential.num_buckets must be at least 1.")
+		}
+		if e.InitialStep <= 0 {
+			return 0, 0, fmt.Errorf("exponential.initial_step must be positive.")
+		}
+		if e.StepMultiplier <= 1 {
+			return 0, 0, fmt.Errorf("exponential.step_multiplier must be greater than 1.")
+		}
+		if e.NumBuckets > maxIntHistogramBuckets {
+			return 0, 0, fmt.Errorf("defines %d buckets, which exceeds the %d bucket cap.", e.NumBuckets, maxIntHistogramBuckets)
+		}
+
+		floor = e.Floor
+		boundary := e.Floor
+		step := e.InitialStep
+		for i := int64(0); i < e.NumBuckets; i++ {
+			if intAddOverflows(boundary, step) {
+				return 0, 0, fmt.Errorf("exponential overflows int64 computing its top boundary.")
+			}
+			boundary += step
+			if i < e.NumBuckets-1 {
+				if intMulOverflows(step, e.StepMultiplier) {
+					return 0, 0, fmt.Errorf("exponential overflows int64 computing its top boundary.")
+				}
+				step *= e.StepMultiplier
+			}
+		}
+		top, numBuckets = boundary, e.NumBuckets
+	}
+
+	if b.Explicit != nil {
+		set++
+		floors := b.Explicit.Floors
+		if len(floors) < 1 {
+			return 0, 0, fmt.Errorf("explicit must list at least 1 floor.")
+		}
+		for i := 1; i < len(floors); i++ {
+			if floors[i] <= floors[i-1] {
+				return 0, 0, fmt.Errorf("explicit floors must be strictly increasing.")
+			}
+		}
+		floor, top, numBuckets = floors[0], floors[len(floors)-1], int64(len(floors))
+	}
+
+	if set != 1 {
+		return 0, 0, fmt.Errorf("Exactly one of linear, exponential or explicit must be set.")
+	}
+
+	if numBuckets > maxIntHistogramBuckets {
+		return 0, 0, fmt.Errorf("defines %d buckets, which exceeds the %d bucket cap.", numBuckets, maxIntHistogramBuckets)
+	}
+
+	return numBuckets, float64(top-floor) / float64(numBuckets), nil
+}
+
+// intMulOverflows reports whether a*b overflows int64.
+func intMulOverflows(a, b int64) bool {
+	if a == 0 || b == 0 {
+		return false
+	}
+	p := a * b
+	return p/b != a
+}
+
+// intAddOverflows reports whether a+b overflows int64.
+func intAddOverflows(a, b int64) bool {
+	s := a + b
+	return (b > 0 && s < a) || (b < 0 && s > a)
+}
+
 func validateStringUsed(m config.MetricDefinition) error {
 	if len(m.EventTypes) > 0 {
 		return fmt.Errorf("event_types must not be set for metrics of type STRING_USED")
@@ -190,7 +328,11 @@ func validateStringUsed(m config.MetricDefinition) error {
 	return nil
 }
 
-func validateCustom(m config.MetricDefinition) error {
+func validateCustom(m config.MetricDefinition, version config_parser.SemVer) error {
+	if !customMetricTypeVersions.Matches(version) {
+		return fmt.Errorf("metric_type CUSTOM requires cobalt_version %v, project is %v.", customMetricTypeVersions, version)
+	}
+
 	if len(m.EventTypes) > 0 {
 		return fmt.Errorf("event_types must not be set for metrics of type CUSTOM")
 	}
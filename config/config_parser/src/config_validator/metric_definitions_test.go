@@ -54,7 +54,7 @@ func makeValidMetricWithName(name string) config.MetricDefinition {
 // Test that makeValidMetric returns a valid metric.
 func TestValidateMakeValidMetric(t *testing.T) {
 	m := makeValidMetric()
-	if err := validateMetricDefinition(m); err != nil {
+	if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err != nil {
 		t.Errorf("Rejected valid metric: %v", err)
 	}
 }
@@ -75,7 +75,7 @@ func TestValidateUniqueMetricId(t *testing.T) {
 
 	metrics := []*config.MetricDefinition{&m1, &m2}
 
-	if err := validateConfiguredMetricDefinitions(metrics); err == nil {
+	if err := validateConfiguredMetricDefinitions(metrics, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err == nil {
 		t.Error("Accepted metric definitions with identical ids.")
 	}
 }
@@ -84,7 +84,7 @@ func TestValidateCorrectMetricId(t *testing.T) {
 	m := makeValidMetric()
 	m.Id += 1
 
-	if err := validateMetricDefinition(m); err == nil {
+	if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err == nil {
 		t.Error("Accepted metric definition with wrong metric id.")
 	}
 }
@@ -93,7 +93,7 @@ func TestValidateCorrectMetricId(t *testing.T) {
 func TestValidateMetricInvalidMetricName(t *testing.T) {
 	m := makeValidMetricWithName("_invalid_name")
 
-	if err := validateMetricDefinition(m); err == nil {
+	if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err == nil {
 		t.Error("Accepted metric definition with invalid name.")
 	}
 }
@@ -102,7 +102,7 @@ func TestValidateMetricInvalidMetricName(t *testing.T) {
 func TestValidateZeroMetricId(t *testing.T) {
 	m := makeValidMetricWithName("NRaMinLNcqiYmgEypLLVGnXymNpxJzqabtbbjLycCMEohvVzZtAYpah")
 
-	if err := validateMetricDefinition(m); err == nil {
+	if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err == nil {
 		t.Error("Accepted metric definition with 0 id.")
 	}
 }
@@ -112,7 +112,7 @@ func TestValidateUnsetMetricType(t *testing.T) {
 	m := makeValidMetric()
 	m.MetricType = config.MetricDefinition_UNSET
 
-	if err := validateMetricDefinition(m); err == nil {
+	if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err == nil {
 		t.Error("Accepted metric definition with unset metric type.")
 	}
 }
@@ -123,13 +123,13 @@ func TestValidateMaxEventTypeIndexOnlySetIfEventOccurred(t *testing.T) {
 	m.MaxEventTypeIndex = 10
 	m.MetricType = config.MetricDefinition_EVENT_OCCURRED
 
-	if err := validateMetricDefinition(m); err != nil {
+	if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err != nil {
 		t.Errorf("Rejected valid metric definition with max_event_type_index set: %v", err)
 	}
 
 	for _, mt := range metricTypesExcept(config.MetricDefinition_EVENT_OCCURRED) {
 		m.MetricType = mt
-		if err := validateMetricDefinition(m); err == nil {
+		if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err == nil {
 			t.Errorf("Accepted metric definition with type %s with max_event_type_index set.", mt)
 		}
 	}
@@ -141,13 +141,14 @@ func TestValidateIntBucketsSetOnlyForIntHistogram(t *testing.T) {
 	m.IntBuckets = &config.IntegerBuckets{}
 	m.MetricType = config.MetricDefinition_INT_HISTOGRAM
 
-	if err := validateMetricDefinition(m); err != nil {
+	m.IntBuckets.Linear = &config.LinearIntegerBuckets{Floor: 0, NumBuckets: 10, StepSize: 5}
+	if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err != nil {
 		t.Errorf("Rejected valid INT_HISTOGRAM metric definition: %v", err)
 	}
 
 	for _, mt := range metricTypesExcept(config.MetricDefinition_INT_HISTOGRAM) {
 		m.MetricType = mt
-		if err := validateMetricDefinition(m); err == nil {
+		if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err == nil {
 			t.Errorf("Accepted metric definition with type %s with int_buckets set.", mt)
 		}
 	}
@@ -160,13 +161,13 @@ func TestValidatePartsSetOnlyForCustom(t *testing.T) {
 	m.MetricType = config.MetricDefinition_CUSTOM
 	m.EventTypes = map[uint32]string{}
 
-	if err := validateMetricDefinition(m); err != nil {
+	if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err != nil {
 		t.Errorf("Rejected valid CUSTOM metric definition: %v", err)
 	}
 
 	for _, mt := range metricTypesExcept(config.MetricDefinition_CUSTOM) {
 		m.MetricType = mt
-		if err := validateMetricDefinition(m); err == nil {
+		if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err == nil {
 			t.Errorf("Accepted metric definition with type %s with parts set.", mt)
 		}
 	}
@@ -177,7 +178,7 @@ func TestValidatePartsNoMetadata(t *testing.T) {
 	m := makeValidMetric()
 	m.MetaData = nil
 
-	if err := validateMetricDefinition(m); err == nil {
+	if err := validateMetricDefinition(m, config_parser.DefaultHasher, config_parser.SemVer{Major: 1}); err == nil {
 		t.Error("Accepted metric definition with no meta_data set.")
 	}
 }
@@ -288,6 +289,110 @@ func TestValidateIntHistogramNoBuckets(t *testing.T) {
 	}
 }
 
+func TestValidateIntHistogramLinear(t *testing.T) {
+	m := makeValidMetric()
+	m.IntBuckets = &config.IntegerBuckets{Linear: &config.LinearIntegerBuckets{Floor: 0, NumBuckets: 10, StepSize: 5}}
+
+	if err := validateIntHistogram(m); err != nil {
+		t.Errorf("Rejected valid linear int_buckets: %v", err)
+	}
+
+	m.IntBuckets.Linear.StepSize = 0
+	if err := validateIntHistogram(m); err == nil {
+		t.Error("Accepted linear int_buckets with a non-positive step_size.")
+	}
+
+	m.IntBuckets.Linear.StepSize = 5
+	m.IntBuckets.Linear.NumBuckets = 0
+	if err := validateIntHistogram(m); err == nil {
+		t.Error("Accepted linear int_buckets with fewer than 1 bucket.")
+	}
+}
+
+func TestValidateIntHistogramExponential(t *testing.T) {
+	m := makeValidMetric()
+	m.IntBuckets = &config.IntegerBuckets{Exponential: &config.ExponentialIntegerBuckets{Floor: 0, NumBuckets: 10, InitialStep: 1, StepMultiplier: 2}}
+
+	if err := validateIntHistogram(m); err != nil {
+		t.Errorf("Rejected valid exponential int_buckets: %v", err)
+	}
+
+	m.IntBuckets.Exponential.StepMultiplier = 1
+	if err := validateIntHistogram(m); err == nil {
+		t.Error("Accepted exponential int_buckets with a step_multiplier of 1.")
+	}
+
+	m.IntBuckets.Exponential.StepMultiplier = 2
+	m.IntBuckets.Exponential.InitialStep = 0
+	if err := validateIntHistogram(m); err == nil {
+		t.Error("Accepted exponential int_buckets with a non-positive initial_step.")
+	}
+}
+
+func TestValidateIntHistogramExponentialOverflow(t *testing.T) {
+	m := makeValidMetric()
+	m.IntBuckets = &config.IntegerBuckets{Exponential: &config.ExponentialIntegerBuckets{Floor: 0, NumBuckets: 100, InitialStep: 1, StepMultiplier: 1 << 40}}
+
+	if err := validateIntHistogram(m); err == nil {
+		t.Error("Accepted exponential int_buckets that overflow int64 computing their top boundary.")
+	}
+}
+
+func TestValidateIntHistogramExplicit(t *testing.T) {
+	m := makeValidMetric()
+	m.IntBuckets = &config.IntegerBuckets{Explicit: &config.ExplicitIntegerBuckets{Floors: []int64{0, 5, 10, 20}}}
+
+	if err := validateIntHistogram(m); err != nil {
+		t.Errorf("Rejected valid explicit int_buckets: %v", err)
+	}
+
+	m.IntBuckets.Explicit.Floors = []int64{0, 5, 5, 20}
+	if err := validateIntHistogram(m); err == nil {
+		t.Error("Accepted explicit int_buckets with non-increasing floors.")
+	}
+}
+
+func TestValidateIntHistogramBucketCountCap(t *testing.T) {
+	m := makeValidMetric()
+	m.IntBuckets = &config.IntegerBuckets{Linear: &config.LinearIntegerBuckets{Floor: 0, NumBuckets: maxIntHistogramBuckets + 1, StepSize: 1}}
+
+	if err := validateIntHistogram(m); err == nil {
+		t.Error("Accepted int_buckets exceeding the bucket count cap.")
+	}
+}
+
+// Tests that a huge exponential.num_buckets is rejected by the bucket count
+// cap before the overflow-checking loop ever runs, so that a maliciously (or
+// just accidentally) huge num_buckets fails fast instead of hanging.
+func TestValidateIntHistogramExponentialBucketCountCapFailsFast(t *testing.T) {
+	m := makeValidMetric()
+	m.IntBuckets = &config.IntegerBuckets{Exponential: &config.ExponentialIntegerBuckets{Floor: 0, NumBuckets: 1000000000, InitialStep: 1, StepMultiplier: 2}}
+
+	done := make(chan error, 1)
+	go func() { done <- validateIntHistogram(m) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Accepted exponential int_buckets exceeding the bucket count cap.")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("validateIntHistogram did not fail fast on a huge exponential.num_buckets.")
+	}
+}
+
+func TestValidateIntHistogramExactlyOneVariant(t *testing.T) {
+	m := makeValidMetric()
+	m.IntBuckets = &config.IntegerBuckets{
+		Linear:      &config.LinearIntegerBuckets{Floor: 0, NumBuckets: 10, StepSize: 5},
+		Exponential: &config.ExponentialIntegerBuckets{Floor: 0, NumBuckets: 10, InitialStep: 1, StepMultiplier: 2},
+	}
+
+	if err := validateIntHistogram(m); err == nil {
+		t.Error("Accepted int_buckets with more than one variant set.")
+	}
+}
+
 func TestValidateStringUsedEventTypesSet(t *testing.T) {
 	m := makeValidMetric()
 	m.EventTypes = map[uint32]string{1: "hello"}
@@ -302,7 +407,7 @@ func TestValidateCustomEventTypesSet(t *testing.T) {
 	m.Parts = map[string]*config.MetricPart{"hello": nil}
 	m.EventTypes = map[uint32]string{1: "hello"}
 
-	if err := validateCustom(m); err == nil {
+	if err := validateCustom(m, config_parser.SemVer{Major: 1}); err == nil {
 		t.Error("Accepted CUSTOM metric with event_types set.")
 	}
 }
@@ -312,7 +417,7 @@ func TestValidateCustomNoParts(t *testing.T) {
 	m.EventTypes = map[uint32]string{}
 	m.Parts = map[string]*config.MetricPart{}
 
-	if err := validateCustom(m); err == nil {
+	if err := validateCustom(m, config_parser.SemVer{Major: 1}); err == nil {
 		t.Error("Accepted CUSTOM metric with no parts.")
 	}
 }
@@ -322,7 +427,7 @@ func TestValidateCustomInvalidPartName(t *testing.T) {
 	m.EventTypes = map[uint32]string{}
 	m.Parts = map[string]*config.MetricPart{"_invalid_name": nil}
 
-	if err := validateCustom(m); err == nil {
+	if err := validateCustom(m, config_parser.SemVer{Major: 1}); err == nil {
 		t.Error("Accepted CUSTOM metric with invalid part name.")
 	}
 }
@@ -0,0 +1,90 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file checks INT_RANGE_HISTOGRAM reports that opt into local
+// differential-privacy noise (via ReportDefinition.LocalPrivacyNoiseLevel)
+// against two invariants that the per-field validation in
+// metric_definitions.go/report_definitions.go can't express on its own:
+// that the noise added can't be amplified past a sensitivity budget by a
+// metric with too many buckets and event types, and that the noise isn't so
+// large relative to the bucket width that the resulting report is useless.
+// The former is an Error; the latter, since "useless" is a judgment call
+// rather than a hard invariant, is only a Warning.
+
+package config_validator
+
+import (
+	"config"
+	"config_parser"
+	"fmt"
+	"math"
+)
+
+// MaxNoiseSensitivityBudget bounds bucket_count * num_event_types for a
+// histogram report with local-DP noise enabled, since each additional bucket
+// and event type multiplies the amount of independently-noised data an
+// attacker can average over to recover the true signal. Operators with a
+// genuine need for a larger budget can raise this at build time.
+var MaxNoiseSensitivityBudget int64 = 100000
+
+// minNoiseUtilityRatio is the minimum acceptable bucket_width / noise_stddev
+// ratio. Below this, the noise added to a bucket is large enough relative to
+// its width that neighboring buckets become difficult to tell apart.
+const minNoiseUtilityRatio = 1.0
+
+// approxNoiseEpsilon maps LocalPrivacyNoiseLevel to the epsilon it
+// corresponds to. These are the same per-level epsilons Cobalt's reporting
+// pipeline uses to calibrate its Laplace noise generators; NONE is omitted
+// since it adds no noise.
+var approxNoiseEpsilon = map[config.ReportDefinition_LocalPrivacyNoiseLevel]float64{
+	config.ReportDefinition_SMALL:  1.0,
+	config.ReportDefinition_MEDIUM: 0.5,
+	config.ReportDefinition_LARGE:  0.1,
+}
+
+// checkPrivacyUtility appends an Issue for every histogram report on c whose
+// local-DP noise either exceeds MaxNoiseSensitivityBudget (an Error) or is
+// large enough relative to its bucket width to likely drown out the signal
+// (a Warning).
+func checkPrivacyUtility(c *config_parser.ProjectConfig, report *ValidationReport) {
+	for _, m := range c.ProjectConfig.MetricDefinitions {
+		if m.MetricType != config.MetricDefinition_INT_HISTOGRAM || m.IntBuckets == nil {
+			continue
+		}
+
+		numBuckets, width, err := intBucketsShape(*m.IntBuckets)
+		if err != nil {
+			// Already reported by the metric-level validation; nothing more
+			// to say here.
+			continue
+		}
+
+		numEventTypes := int64(len(m.EventTypes))
+		if numEventTypes == 0 {
+			numEventTypes = 1
+		}
+
+		for _, r := range m.Reports {
+			epsilon, ok := approxNoiseEpsilon[r.LocalPrivacyNoiseLevel]
+			if !ok {
+				continue
+			}
+
+			sensitivity := numBuckets * numEventTypes
+			if sensitivity > MaxNoiseSensitivityBudget {
+				report.addMetricReportError(c, m.MetricName, r.ReportName, ECodeSensitivityBudgetExceeded,
+					fmt.Sprintf("Report '%s' has %d buckets across %d event types (%d total), which exceeds the noise sensitivity budget of %d.", r.ReportName, numBuckets, numEventTypes, sensitivity, MaxNoiseSensitivityBudget))
+				continue
+			}
+
+			// The Laplace mechanism's noise has standard deviation
+			// sqrt(2) * scale, where scale is 1/epsilon.
+			stddev := math.Sqrt2 / epsilon
+			if width > 0 && width/stddev < minNoiseUtilityRatio {
+				report.addMetricReportWarning(c, m.MetricName, r.ReportName, WCodeNoiseDwarfsSignal,
+					fmt.Sprintf("Report '%s' has bucket width %.3g but local-DP noise of stddev %.3g; adjacent buckets will be difficult to tell apart.", r.ReportName, width, stddev))
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_validator
+
+import (
+	"config"
+	"config_parser"
+	"testing"
+)
+
+func makeValidIntHistogramMetric() config.MetricDefinition {
+	m := makeValidMetric()
+	m.MetricType = config.MetricDefinition_INT_HISTOGRAM
+	m.EventTypes = map[uint32]string{}
+	m.IntBuckets = &config.IntegerBuckets{Linear: &config.LinearIntegerBuckets{Floor: 0, NumBuckets: 10, StepSize: 10}}
+	m.Reports = []*config.ReportDefinition{{
+		ReportName: "the_report_name",
+		ReportType: config.ReportDefinition_INT_RANGE_HISTOGRAM,
+	}}
+	return m
+}
+
+func TestCheckPrivacyUtilityNoNoiseIsSilent(t *testing.T) {
+	m := makeValidIntHistogramMetric()
+	c := &config_parser.ProjectConfig{CobaltVersion: config_parser.CobaltVersion1}
+	c.ProjectConfig.MetricDefinitions = []*config.MetricDefinition{&m}
+
+	report := &ValidationReport{}
+	checkPrivacyUtility(c, report)
+	if len(report.Issues) != 0 {
+		t.Errorf("Expected no issues for a report with no local-DP noise, got %v", report.Issues)
+	}
+}
+
+func TestCheckPrivacyUtilityLargeNoiseOnNarrowBucketsWarns(t *testing.T) {
+	m := makeValidIntHistogramMetric()
+	m.Reports[0].LocalPrivacyNoiseLevel = config.ReportDefinition_LARGE
+	c := &config_parser.ProjectConfig{CobaltVersion: config_parser.CobaltVersion1}
+	c.ProjectConfig.MetricDefinitions = []*config.MetricDefinition{&m}
+
+	report := &ValidationReport{}
+	checkPrivacyUtility(c, report)
+
+	found := false
+	for _, i := range report.Issues {
+		if i.Code == WCodeNoiseDwarfsSignal {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a %s warning for large noise relative to bucket width, got %v", WCodeNoiseDwarfsSignal, report.Issues)
+	}
+}
+
+func TestCheckPrivacyUtilitySensitivityBudgetExceeded(t *testing.T) {
+	m := makeValidIntHistogramMetric()
+	m.EventTypes = map[uint32]string{0: "a", 1: "b"}
+	m.Reports[0].LocalPrivacyNoiseLevel = config.ReportDefinition_SMALL
+	c := &config_parser.ProjectConfig{CobaltVersion: config_parser.CobaltVersion1}
+	c.ProjectConfig.MetricDefinitions = []*config.MetricDefinition{&m}
+
+	oldBudget := MaxNoiseSensitivityBudget
+	defer func() { MaxNoiseSensitivityBudget = oldBudget }()
+	MaxNoiseSensitivityBudget = 1
+
+	report := &ValidationReport{}
+	checkPrivacyUtility(c, report)
+
+	found := false
+	for _, i := range report.Issues {
+		if i.Code == ECodeSensitivityBudgetExceeded {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a %s error when the sensitivity budget is exceeded, got %v", ECodeSensitivityBudgetExceeded, report.Issues)
+	}
+}
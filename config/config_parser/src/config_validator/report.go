@@ -0,0 +1,182 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// This file contains ValidationReport, a structured alternative to the plain
+// fmt.Errorf strings returned by validateConfiguredMetricDefinitions and
+// friends. A ValidationReport carries a severity per issue plus a stable
+// error code and the offending customer/project/metric/report names, so a CI
+// system can render it as JSON, and a human reviewing a large config change
+// doesn't have to fix one problem per round-trip to even see the next one.
+//
+// Cobalt 1.0 configs go through collectMetricDefinitionIssues, which
+// accumulates one Issue per failing metric/report instead of stopping at the
+// first one. Cobalt 0.1 configs are legacy and still validated fail-fast via
+// ValidateProjectConfig, wrapping its single error into one Issue; that
+// format is frozen and not worth the risk of touching.
+
+package config_validator
+
+import (
+	"config_parser"
+	"encoding/json"
+	"fmt"
+)
+
+// Severity classifies how serious a validation Issue is.
+type Severity int
+
+const (
+	// Error issues must be fixed; ValidateAll's caller should treat them as
+	// build failures.
+	SeverityError Severity = iota
+	// Warning issues are suspicious but do not block a build.
+	SeverityWarning
+	// Info issues are purely informational.
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "Error"
+	case SeverityWarning:
+		return "Warning"
+	default:
+		return "Info"
+	}
+}
+
+// Issue codes. Error codes begin with "E_", warning codes with "W_", so a
+// reader (or a grep) can tell severity from the code alone.
+const (
+	ECodeValidationFailed           = "E_VALIDATION_FAILED"
+	ECodeSensitivityBudgetExceeded  = "E_SENSITIVITY_BUDGET_EXCEEDED"
+	ECodeDuplicateReportId          = "E_DUPLICATE_REPORT_ID"
+	ECodeMissingSystemProfileField  = "E_MISSING_SYSTEM_PROFILE_FIELD"
+	ECodeDuplicateProjectId         = "E_DUPLICATE_PROJECT_ID"
+	ECodeDuplicateProjectName       = "E_DUPLICATE_PROJECT_NAME"
+	WCodeExpirationNear             = "W_EXPIRATION_NEAR"
+	WCodeNoiseDwarfsSignal          = "W_NOISE_DWARFS_SIGNAL"
+	WCodeInconsistentEventTypeLabel = "W_INCONSISTENT_EVENT_TYPE_LABEL"
+)
+
+// Issue is a single finding from ValidateAll: a severity, a stable code for
+// programmatic handling, the names of whatever config elements it concerns,
+// and a human readable message. No check in this package sets
+// File/Line/Column, since ProjectConfig carries no YAML source positions, so
+// they are always zero and left out of RenderJSON's output.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Customer string   `json:"customer,omitempty"`
+	Project  string   `json:"project,omitempty"`
+	Metric   string   `json:"metric,omitempty"`
+	Report   string   `json:"report,omitempty"`
+	Message  string   `json:"message"`
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+}
+
+func (i Issue) String() string {
+	location := ""
+	if i.File != "" {
+		location = fmt.Sprintf(" (%s:%d:%d)", i.File, i.Line, i.Column)
+	}
+	return fmt.Sprintf("[%s %s] %s%s", i.Severity, i.Code, i.Message, location)
+}
+
+// ValidationReport accumulates every Issue found while validating a project.
+type ValidationReport struct {
+	Issues []Issue
+}
+
+func (r *ValidationReport) addError(c *config_parser.ProjectConfig, code, message string) {
+	r.Issues = append(r.Issues, Issue{
+		Severity: SeverityError,
+		Code:     code,
+		Customer: c.CustomerName,
+		Project:  c.ProjectName,
+		Message:  message,
+	})
+}
+
+func (r *ValidationReport) addMetricReportError(c *config_parser.ProjectConfig, metric, reportName, code, message string) {
+	r.Issues = append(r.Issues, Issue{
+		Severity: SeverityError,
+		Code:     code,
+		Customer: c.CustomerName,
+		Project:  c.ProjectName,
+		Metric:   metric,
+		Report:   reportName,
+		Message:  message,
+	})
+}
+
+func (r *ValidationReport) addMetricReportWarning(c *config_parser.ProjectConfig, metric, reportName, code, message string) {
+	r.Issues = append(r.Issues, Issue{
+		Severity: SeverityWarning,
+		Code:     code,
+		Customer: c.CustomerName,
+		Project:  c.ProjectName,
+		Metric:   metric,
+		Report:   reportName,
+		Message:  message,
+	})
+}
+
+// HasErrors reports whether the report contains any Issue of SeverityError.
+// A CI system should only exit non-zero when this is true; Warning and Info
+// issues should not block a build.
+func (r *ValidationReport) HasErrors() bool {
+	for _, i := range r.Issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderText renders the report as human readable text, one issue per line.
+func (r *ValidationReport) RenderText() string {
+	s := ""
+	for _, i := range r.Issues {
+		s += i.String() + "\n"
+	}
+	return s
+}
+
+// RenderJSON renders the report as a JSON array of issues, for CI systems.
+func (r *ValidationReport) RenderJSON() ([]byte, error) {
+	return json.MarshalIndent(r.Issues, "", "  ")
+}
+
+// ValidateAll validates c the same way ValidateProjectConfig does, but
+// returns every issue found as a ValidationReport instead of stopping at (or
+// returning) the first error. It also runs checkPrivacyUtility and
+// checkCrossMetricConsistency, which ValidateProjectConfig does not, so a
+// config that ValidateProjectConfig accepts can still yield Error issues here.
+func ValidateAll(c *config_parser.ProjectConfig) *ValidationReport {
+	report := &ValidationReport{}
+
+	if c.CobaltVersion == config_parser.CobaltVersion1 {
+		if len(c.ProjectConfig.EncodingConfigs) > 0 || len(c.ProjectConfig.MetricConfigs) > 0 || len(c.ProjectConfig.ReportConfigs) > 0 {
+			report.addError(c, ECodeValidationFailed, "Version 1 projects cannot contain encoding_config, metric_config or report_config entries.")
+		}
+
+		hasher, err := config_parser.HasherByName(c.HashAlgorithm)
+		if err != nil {
+			report.addError(c, ECodeValidationFailed, err.Error())
+		} else {
+			collectMetricDefinitionIssues(c, c.ProjectConfig.MetricDefinitions, hasher, report)
+		}
+	} else if err := ValidateProjectConfig(c); err != nil {
+		report.addError(c, ECodeValidationFailed, err.Error())
+	}
+
+	checkPrivacyUtility(c, report)
+	checkCrossMetricConsistency(c, report)
+
+	return report
+}
@@ -47,9 +47,9 @@ var allowedReportTypes = map[config.MetricDefinition_MetricType]map[config.Repor
 	},
 }
 
-func validateReportDefinitions(m config.MetricDefinition) error {
+func validateReportDefinitions(m config.MetricDefinition, hasher config_parser.IdHasher) error {
 	for _, r := range m.Reports {
-		if err := validateReportDefinitionForMetric(m, *r); err != nil {
+		if err := validateReportDefinitionForMetric(m, *r, hasher); err != nil {
 			return fmt.Errorf("Error validating report '%s': %v", r.ReportName, err)
 		}
 	}
@@ -58,12 +58,12 @@ func validateReportDefinitions(m config.MetricDefinition) error {
 }
 
 // Validate a single instance of a ReportDefinition with its associated metric.
-func validateReportDefinitionForMetric(m config.MetricDefinition, r config.ReportDefinition) error {
+func validateReportDefinitionForMetric(m config.MetricDefinition, r config.ReportDefinition, hasher config_parser.IdHasher) error {
 	if err := validateReportType(m.MetricType, r.ReportType); err != nil {
 		return err
 	}
 
-	if err := validateReportDefinition(r); err != nil {
+	if err := validateReportDefinition(r, hasher); err != nil {
 		return err
 	}
 
@@ -71,12 +71,12 @@ func validateReportDefinitionForMetric(m config.MetricDefinition, r config.Repor
 }
 
 // Validate a single instance of a ReportDefinition.
-func validateReportDefinition(r config.ReportDefinition) error {
+func validateReportDefinition(r config.ReportDefinition, hasher config_parser.IdHasher) error {
 	if !validNameRegexp.MatchString(r.ReportName) {
 		return fmt.Errorf("Invalid report name. Report names must match the regular expression '%v'.", validNameRegexp)
 	}
 
-	if r.Id != config_parser.IdFromName(r.ReportName) {
+	if r.Id != hasher.Hash(r.ReportName) {
 		return fmt.Errorf("Report id specified in the config file. Report ids may not be set by users.")
 	}
 
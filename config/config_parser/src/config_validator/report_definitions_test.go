@@ -27,7 +27,7 @@ func makeValidReportWithName(name string) config.ReportDefinition {
 // Test that makeValidReport returns a valid report.
 func TestValidateMakeValidReport(t *testing.T) {
 	r := makeValidReport()
-	if err := validateReportDefinition(r); err != nil {
+	if err := validateReportDefinition(r, config_parser.DefaultHasher); err != nil {
 		t.Errorf("Rejected valid report: %v", err)
 	}
 }
@@ -37,7 +37,7 @@ func TestValidateCorrectReportId(t *testing.T) {
 	r := makeValidReport()
 	r.Id += 1
 
-	if err := validateReportDefinition(r); err == nil {
+	if err := validateReportDefinition(r, config_parser.DefaultHasher); err == nil {
 		t.Error("Accepted report with wrong report id.")
 	}
 }
@@ -45,7 +45,7 @@ func TestValidateCorrectReportId(t *testing.T) {
 func TestValidateInvalidName(t *testing.T) {
 	r := makeValidReportWithName("_invalid_name")
 
-	if err := validateReportDefinition(r); err == nil {
+	if err := validateReportDefinition(r, config_parser.DefaultHasher); err == nil {
 		t.Error("Accepted report with invalid name.")
 	}
 }
@@ -53,7 +53,7 @@ func TestValidateInvalidName(t *testing.T) {
 func TestValidateZeroReportId(t *testing.T) {
 	r := makeValidReportWithName("NRaMinLNcqiYmgEypLLVGnXymNpxJzqabtbbjLycCMEohvVzZtAYpah")
 
-	if err := validateReportDefinition(r); err == nil {
+	if err := validateReportDefinition(r, config_parser.DefaultHasher); err == nil {
 		t.Error("Accepted report with 0 id.")
 	}
 }
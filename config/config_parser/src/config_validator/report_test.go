@@ -0,0 +1,41 @@
+// Copyright 2018 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package config_validator
+
+import (
+	"config_parser"
+	"testing"
+)
+
+func TestValidateAllValidProject(t *testing.T) {
+	m := makeValidMetric()
+	c := &config_parser.ProjectConfig{
+		ProjectName:   "the_project",
+		CobaltVersion: config_parser.CobaltVersion1,
+	}
+	c.ProjectConfig.MetricDefinitions = append(c.ProjectConfig.MetricDefinitions, &m)
+
+	report := ValidateAll(c)
+	if report.HasErrors() {
+		t.Errorf("Expected no errors for a valid project, got: %v", report.RenderText())
+	}
+}
+
+func TestValidateAllInvalidProject(t *testing.T) {
+	c := &config_parser.ProjectConfig{
+		ProjectName:   "the_project",
+		CobaltVersion: config_parser.CobaltVersion1,
+	}
+	c.ProjectConfig.EncodingConfigs = append(c.ProjectConfig.EncodingConfigs, nil)
+
+	report := ValidateAll(c)
+	if !report.HasErrors() {
+		t.Errorf("Expected an error for an invalid project, got none.")
+	}
+
+	if report.Issues[0].Code != ECodeValidationFailed {
+		t.Errorf("Unexpected issue code: %v", report.Issues[0].Code)
+	}
+}
@@ -16,10 +16,43 @@ func ValidateProjectConfig(c *config_parser.ProjectConfig) (err error) {
 			return fmt.Errorf("Error in configuration for project %s: %v", c.ProjectName, err)
 		}
 	} else {
-		if err = validateConfigV1(&c.ProjectConfig); err != nil {
+		hasher, err := config_parser.HasherByName(c.HashAlgorithm)
+		if err != nil {
 			return fmt.Errorf("Error in configuration for project %s: %v", c.ProjectName, err)
 		}
+		if err = validateConfigV1(&c.ProjectConfig, hasher, c.Version); err != nil {
+			return fmt.Errorf("Error in configuration for project %s: %v", c.ProjectName, err)
+		}
+	}
+	return nil
+}
+
+// ValidateProjectConfigWithReservedIds runs ValidateProjectConfig and then,
+// for Cobalt 1.0 projects, checks every metric and report id against
+// registry, rejecting a new metric/report whose hashed id collides with one
+// reserved for a different name, and reserving the ids it uses. The caller
+// is responsible for persisting registry (e.g. with
+// config_parser.WriteReservedIdsRegistry) once validation succeeds.
+func ValidateProjectConfigWithReservedIds(c *config_parser.ProjectConfig, registry *config_parser.ReservedIdsRegistry) error {
+	if err := ValidateProjectConfig(c); err != nil {
+		return err
 	}
+
+	if c.CobaltVersion != config_parser.CobaltVersion1 {
+		return nil
+	}
+
+	for _, m := range c.ProjectConfig.MetricDefinitions {
+		if err := registry.ReserveMetric(m.Id, m.MetricName); err != nil {
+			return fmt.Errorf("Error in configuration for project %s: %v", c.ProjectName, err)
+		}
+		for _, r := range m.Reports {
+			if err := registry.ReserveReport(r.Id, r.ReportName); err != nil {
+				return fmt.Errorf("Error in configuration for project %s: %v", c.ProjectName, err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -52,13 +85,15 @@ func validateConfigV0(config *config.CobaltConfig) (err error) {
 	return nil
 }
 
-// Validate a project config for Cobalt 1.0.
-func validateConfigV1(config *config.CobaltConfig) (err error) {
+// Validate a project config for Cobalt 1.0. version is the project's
+// cobalt_version, used to gate version-specific rules (see
+// validateConfiguredMetricDefinitions).
+func validateConfigV1(config *config.CobaltConfig, hasher config_parser.IdHasher, version config_parser.SemVer) (err error) {
 	if len(config.EncodingConfigs) > 0 || len(config.MetricConfigs) > 0 || len(config.ReportConfigs) > 0 {
 		return fmt.Errorf("Version 1 projects cannot contain encoding_config, metric_config or report_config entries.")
 	}
 
-	if err = validateConfiguredMetricDefinitions(config.MetricDefinitions); err != nil {
+	if err = validateConfiguredMetricDefinitions(config.MetricDefinitions, hasher, version); err != nil {
 		return err
 	}
 	return nil